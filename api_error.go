@@ -0,0 +1,149 @@
+package go_klarna
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// correlationIDHeader is the response header Klarna uses to echo the correlation id of a request
+	correlationIDHeader = "Klarna-Correlation-Id"
+
+	// maxErrorBodySize caps the amount of bytes read from an error response body
+	maxErrorBodySize = 1 << 20
+
+	// Klarna error codes, see https://developers.klarna.com/api/#errors
+	ErrorCodeBadValue          = "BAD_VALUE"
+	ErrorCodeNoSuchOrder       = "NO_SUCH_ORDER"
+	ErrorCodeNotAllowed        = "NOT_ALLOWED"
+	ErrorCodeCaptureNotAllowed = "CAPTURE_NOT_ALLOWED"
+	ErrorCodeRefundNotAllowed  = "REFUND_NOT_ALLOWED"
+)
+
+var (
+	// ErrBadRequest error describes that Klarna API rejected the request because of invalid input
+	ErrBadRequest = errors.New("the request was rejected by Klarna API, some fields constraint was violated")
+	// ErrRateLimited error describes that Klarna API refused the request because of too many requests
+	ErrRateLimited = errors.New("too many requests, rate limited by Klarna API")
+)
+
+// APIError type is the error returned for every non successful response of the Klarna API, it carries the
+// details that Klarna sent back together with the request that caused it
+type APIError struct {
+	StatusCode    int      `json:"-"`
+	Method        string   `json:"-"`
+	Path          string   `json:"-"`
+	ErrorCode     string   `json:"error_code,omitempty"`
+	ErrorMessages []string `json:"error_messages,omitempty"`
+	CorrelationID string   `json:"correlation_id,omitempty"`
+	// RetryAfter is the delay Klarna asked to wait through the Retry-After header before trying again
+	RetryAfter time.Duration `json:"-"`
+}
+
+// Error method formats the error with all the information required to open a ticket with Klarna
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("klarna: %s %s: %d %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+	if "" != e.ErrorCode {
+		msg += ": " + e.ErrorCode
+	}
+	if 0 < len(e.ErrorMessages) {
+		msg += ": " + strings.Join(e.ErrorMessages, "; ")
+	}
+	if "" != e.CorrelationID {
+		msg += " (correlation_id: " + e.CorrelationID + ")"
+	}
+
+	return msg
+}
+
+// Is method makes the APIError comparable with the package sentinel errors through errors.Is
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return http.StatusBadRequest == e.StatusCode
+	case ErrOrderCreate:
+		return http.StatusBadRequest == e.StatusCode && e.isOrderCreation()
+	case ErrUnAuthorized:
+		return http.StatusUnauthorized == e.StatusCode
+	case ErrReadOnlyResource:
+		return http.StatusForbidden == e.StatusCode
+	case ErrOrderNotFound:
+		return http.StatusNotFound == e.StatusCode
+	case ErrRateLimited:
+		return http.StatusTooManyRequests == e.StatusCode
+	case ServiceUnavailable:
+		switch e.StatusCode {
+		case http.StatusBadRequest,
+			http.StatusUnauthorized,
+			http.StatusForbidden,
+			http.StatusNotFound,
+			http.StatusTooManyRequests:
+			return false
+		}
+		return true
+	}
+
+	return false
+}
+
+// isOrderCreation method tells whether the failed request was meant to create a checkout or payment order
+func (e *APIError) isOrderCreation() bool {
+	if http.MethodPost != e.Method {
+		return false
+	}
+
+	return checkoutEndPoint == e.Path ||
+		(strings.HasPrefix(e.Path, paymentOrdersApiURL+"/") && strings.HasSuffix(e.Path, "/order"))
+}
+
+// HasErrorCode function reports whether the given error is an APIError carrying the given Klarna error code
+func HasErrorCode(err error, code string) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	return code == apiErr.ErrorCode
+}
+
+// IsNotAllowed function reports whether Klarna refused the operation with NOT_ALLOWED
+func IsNotAllowed(err error) bool {
+	return HasErrorCode(err, ErrorCodeNotAllowed)
+}
+
+// IsCaptureNotAllowed function reports whether Klarna refused a capture with CAPTURE_NOT_ALLOWED
+func IsCaptureNotAllowed(err error) bool {
+	return HasErrorCode(err, ErrorCodeCaptureNotAllowed)
+}
+
+// IsRefundNotAllowed function reports whether Klarna refused a refund with REFUND_NOT_ALLOWED
+func IsRefundNotAllowed(err error) bool {
+	return HasErrorCode(err, ErrorCodeRefundNotAllowed)
+}
+
+// newAPIError function builds an APIError out of a failed response, the body of the response is consumed
+func newAPIError(res *http.Response) *APIError {
+	e := new(APIError)
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+	if 0 < len(body) {
+		// Klarna does not always answer with a JSON body, e.g. when a proxy in between fails
+		_ = json.Unmarshal(body, e)
+	}
+
+	e.StatusCode = res.StatusCode
+	if nil != res.Request {
+		e.Method = res.Request.Method
+		e.Path = res.Request.URL.Path
+	}
+	if "" == e.CorrelationID {
+		e.CorrelationID = res.Header.Get(correlationIDHeader)
+	}
+	e.RetryAfter = parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+
+	return e
+}
@@ -0,0 +1,98 @@
+package go_klarna
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func setupErrorMux(path string, status int, body string) {
+	testingMux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Klarna-Correlation-Id", "header-correlation-id")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	})
+}
+
+func TestAPIError_CaptureNotAllowed(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	setupErrorMux(
+		"/ordermanagement/v1/orders/abc/captures",
+		http.StatusForbidden,
+		`{"error_code":"CAPTURE_NOT_ALLOWED","error_messages":["order is cancelled"],"correlation_id":"corr-123"}`,
+	)
+
+	c := testingClient()
+	err := NewOrderManagement(c).CreateCapture("abc", &CreateCapture{})
+
+	var apiErr *APIError
+	assertions.True(errors.As(err, &apiErr))
+	assertions.Equal(http.StatusForbidden, apiErr.StatusCode)
+	assertions.Equal(http.MethodPost, apiErr.Method)
+	assertions.Equal("/ordermanagement/v1/orders/abc/captures", apiErr.Path)
+	assertions.Equal("CAPTURE_NOT_ALLOWED", apiErr.ErrorCode)
+	assertions.Equal([]string{"order is cancelled"}, apiErr.ErrorMessages)
+	assertions.Equal("corr-123", apiErr.CorrelationID)
+	assertions.True(IsCaptureNotAllowed(err))
+	assertions.False(IsRefundNotAllowed(err))
+	assertions.ErrorIs(err, ErrReadOnlyResource)
+	assertions.NotErrorIs(err, ServiceUnavailable)
+}
+
+func TestAPIError_BadRequestOnRefund(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	setupErrorMux(
+		"/ordermanagement/v1/orders/abc/refunds",
+		http.StatusBadRequest,
+		`{"error_code":"REFUND_NOT_ALLOWED","error_messages":["amount too high"]}`,
+	)
+
+	c := testingClient()
+	err := NewOrderManagement(c).CreateRefund("abc", &OrderManagementRefund{})
+
+	assertions.ErrorIs(err, ErrBadRequest)
+	assertions.NotErrorIs(err, ErrOrderCreate)
+	assertions.True(IsRefundNotAllowed(err))
+	assertions.Contains(err.Error(), "header-correlation-id")
+}
+
+func TestAPIError_BadRequestOnOrderCreation(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	setupErrorMux("/checkout/v3/orders", http.StatusBadRequest, `{"error_code":"BAD_VALUE"}`)
+
+	c := testingClient()
+	err := NewCheckoutSrv(c).CreateNewOrder(&CheckoutOrder{})
+
+	assertions.ErrorIs(err, ErrOrderCreate)
+	assertions.ErrorIs(err, ErrBadRequest)
+	assertions.True(HasErrorCode(err, ErrorCodeBadValue))
+}
+
+func TestAPIError_NonJSONBody(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	setupErrorMux("/ordermanagement/v1/orders/abc", http.StatusBadGateway, "<html>bad gateway</html>")
+
+	c := testingClient()
+	_, err := NewOrderManagement(c).GetOrder("abc")
+
+	var apiErr *APIError
+	assertions.True(errors.As(err, &apiErr))
+	assertions.Equal(http.StatusBadGateway, apiErr.StatusCode)
+	assertions.Empty(apiErr.ErrorCode)
+	assertions.ErrorIs(err, ServiceUnavailable)
+	assertions.NotErrorIs(err, ErrOrderNotFound)
+}
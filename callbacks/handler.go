@@ -0,0 +1,281 @@
+// Package callbacks implements the merchant side of the Klarna Checkout API callbacks: push, validation,
+// address_update, shipping_option_update, country_change and notification
+package callbacks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	klarna "github.com/Flaconi/go-klarna"
+	"log/slog"
+	"net/http"
+	"path"
+	"time"
+)
+
+const (
+	// Paths of the callbacks, relative to the base URL the Handler is served at
+	PushPath                 = "/push"
+	ValidationPath           = "/validation"
+	AddressUpdatePath        = "/address_update"
+	ShippingOptionUpdatePath = "/shipping_option_update"
+	CountryChangePath        = "/country_change"
+	NotificationPath         = "/notification"
+
+	// OrderIDParam is the query parameter Klarna passes the order id of the push in, see MerchantURLs
+	OrderIDParam = "klarna_order_id"
+
+	// maxPayloadSize caps the size of the callback payloads read
+	maxPayloadSize = 1 << 20
+)
+
+type (
+	// Config type holds the settings of the callbacks Handler
+	Config struct {
+		// ValidationErrorURL is the page the customer is redirected to when the order is rejected by Validate and
+		// there is no error page for the reason. The {reason} and {order_id} placeholders are replaced, without any
+		// page the customer is sent back to the checkout page of the order. Klarna only rejects an order answered with
+		// a redirect, any other failure of the callback places it
+		ValidationErrorURL string
+		// ErrorPages maps the reasons of RejectWithReason to the error page the customer is redirected to, the
+		// {reason} and {order_id} placeholders are replaced
+		ErrorPages map[string]string
+		// ValidationTimeout is the time the re-fetch of the order by Orders and the merchant have to decide on the
+		// validation, defaults to DefaultValidationTimeout
+		ValidationTimeout time.Duration
+		// DefaultDecision applies when the validation is not decided in time, the order can not be re-fetched or the
+		// merchant returns an error, its zero value accepts the order
+		DefaultDecision ValidationDecision
+		// Secret signs the token Handler.MerchantURLs embeds in the callback URLs, the callbacks without a valid token
+		// are answered with 403. Empty disables the verification
+		Secret []byte
+		// Orders re-fetches the order of every callback from Klarna before the payload is trusted, the callbacks for
+		// an unknown order or one created with another token are answered with 403. The validation callback is handed
+		// the fetched order. Nil disables the re-fetch
+		Orders klarna.CheckoutContextSrv
+		// PushProcessor handles the push callback instead of Merchant.Push when set
+		PushProcessor *PushProcessor
+		// Logger logs the errors returned by the merchant, nil disables logging
+		Logger *slog.Logger
+	}
+
+	// Handler type is an http.Handler serving every callback of the Klarna Checkout API, dispatched by the last
+	// segment of the request path. The callbacks can also be served one by one, e.g. Handler.Push
+	Handler struct {
+		merchant Merchant
+		config   Config
+	}
+
+	// updateResponse type is the shape of the answer to the update callbacks, it only holds the fields Klarna
+	// accepts to be updated
+	updateResponse struct {
+		PurchaseCurrency string                   `json:"purchase_currency,omitempty"`
+		OrderAmount      int                      `json:"order_amount"`
+		OrderTaxAmount   int                      `json:"order_tax_amount"`
+		OrderLines       []*klarna.Line           `json:"order_lines"`
+		ShippingOptions  []*klarna.ShippingOption `json:"shipping_options,omitempty"`
+		MerchantData     string                   `json:"merchant_data,omitempty"`
+	}
+
+	// updateFunc type is one of the update callbacks of Merchant
+	updateFunc func(ctx context.Context, order *klarna.CheckoutOrder) (*klarna.CheckoutOrder, error)
+)
+
+// NewHandler factory method
+func NewHandler(m Merchant, c Config) *Handler {
+	return &Handler{merchant: m, config: c}
+}
+
+// ServeHTTP method dispatches the callback to its handler by the last segment of the request path
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch "/" + path.Base(r.URL.Path) {
+	case PushPath:
+		h.servePush(w, r)
+	case ValidationPath:
+		h.serveValidation(w, r)
+	case AddressUpdatePath:
+		h.serveUpdate(w, r, "address_update", h.merchant.AddressUpdate)
+	case ShippingOptionUpdatePath:
+		h.serveUpdate(w, r, "shipping_option_update", h.merchant.ShippingOptionUpdate)
+	case CountryChangePath:
+		h.serveUpdate(w, r, "country_change", h.merchant.CountryChange)
+	case NotificationPath:
+		h.serveNotification(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Push method returns the handler of the push callback
+func (h *Handler) Push() http.Handler {
+	return http.HandlerFunc(h.servePush)
+}
+
+// Validation method returns the handler of the validation callback
+func (h *Handler) Validation() http.Handler {
+	return http.HandlerFunc(h.serveValidation)
+}
+
+// AddressUpdate method returns the handler of the address_update callback
+func (h *Handler) AddressUpdate() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.serveUpdate(w, r, "address_update", h.merchant.AddressUpdate)
+	})
+}
+
+// ShippingOptionUpdate method returns the handler of the shipping_option_update callback
+func (h *Handler) ShippingOptionUpdate() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.serveUpdate(w, r, "shipping_option_update", h.merchant.ShippingOptionUpdate)
+	})
+}
+
+// CountryChange method returns the handler of the country_change callback
+func (h *Handler) CountryChange() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.serveUpdate(w, r, "country_change", h.merchant.CountryChange)
+	})
+}
+
+// Notification method returns the handler of the notification callback
+func (h *Handler) Notification() http.Handler {
+	return http.HandlerFunc(h.serveNotification)
+}
+
+// servePush method answers 200 once the merchant, or the Config.PushProcessor, handled the push, and 500 otherwise
+// so Klarna pushes again
+func (h *Handler) servePush(w http.ResponseWriter, r *http.Request) {
+	if !allowPost(w, r) {
+		return
+	}
+
+	orderID := r.URL.Query().Get(OrderIDParam)
+	if "" == orderID {
+		http.Error(w, "missing "+OrderIDParam, http.StatusBadRequest)
+		return
+	}
+	if _, ok := h.authenticate(w, r, "push", orderID); !ok {
+		return
+	}
+
+	push := h.merchant.Push
+	if nil != h.config.PushProcessor {
+		push = h.config.PushProcessor.Push
+	}
+	err := push(r.Context(), orderID)
+	var markErr *MarkError
+	if errors.As(err, &markErr) {
+		// the order is completed and acknowledged, pushing it again would not help
+		h.log(r, "push", err)
+		err = nil
+	}
+	if nil != err {
+		h.fail(w, r, "push", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// serveUpdate method answers an update callback with the order updated by the merchant, or with a 400 carrying the
+// rejection
+func (h *Handler) serveUpdate(w http.ResponseWriter, r *http.Request, callback string, update updateFunc) {
+	order, ok := decode[klarna.CheckoutOrder](w, r)
+	if !ok {
+		return
+	}
+	if _, ok = h.authenticate(w, r, callback, order.ID); !ok {
+		return
+	}
+
+	updated, err := update(r.Context(), order)
+	var rejection *Rejection
+	if errors.As(err, &rejection) {
+		writeJSON(w, http.StatusBadRequest, rejection)
+		return
+	}
+	if nil != err {
+		h.fail(w, r, callback, err)
+		return
+	}
+	if nil == updated {
+		updated = order
+	}
+
+	writeJSON(w, http.StatusOK, updateResponse{
+		PurchaseCurrency: updated.PurchaseCurrency,
+		OrderAmount:      updated.OrderAmount,
+		OrderTaxAmount:   updated.OrderTaxAmount,
+		OrderLines:       updated.OrderLines,
+		ShippingOptions:  updated.ShippingOptions,
+		MerchantData:     updated.MerchantData,
+	})
+}
+
+// serveNotification method answers 200 once the merchant handled the notification
+func (h *Handler) serveNotification(w http.ResponseWriter, r *http.Request) {
+	n, ok := decode[Notification](w, r)
+	if !ok {
+		return
+	}
+	if _, ok = h.authenticate(w, r, "notification", n.OrderID); !ok {
+		return
+	}
+
+	if err := h.merchant.Notification(r.Context(), n); nil != err {
+		h.fail(w, r, "notification", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// fail method answers a callback the merchant failed to handle with 500, Klarna retries such callbacks
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, callback string, err error) {
+	h.log(r, callback, err)
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// log method logs an error returned by the merchant
+func (h *Handler) log(r *http.Request, callback string, err error) {
+	if nil == h.config.Logger {
+		return
+	}
+	h.config.Logger.LogAttrs(r.Context(), slog.LevelWarn, "klarna callback failed",
+		slog.String("callback", callback),
+		slog.String("error", err.Error()),
+	)
+}
+
+// allowPost function answers 405 to anything but a POST request, Klarna sends every callback as POST
+func allowPost(w http.ResponseWriter, r *http.Request) bool {
+	if http.MethodPost == r.Method {
+		return true
+	}
+	w.Header().Set("Allow", http.MethodPost)
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+	return false
+}
+
+// decode function reads the JSON payload of a callback, a malformed one is answered with 400
+func decode[T any](w http.ResponseWriter, r *http.Request) (*T, bool) {
+	if !allowPost(w, r) {
+		return nil, false
+	}
+
+	v := new(T)
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPayloadSize)).Decode(v); nil != err {
+		http.Error(w, "malformed payload: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return v, true
+}
+
+// writeJSON function writes the value as JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
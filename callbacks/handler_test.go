@@ -0,0 +1,161 @@
+package callbacks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	klarna "github.com/Flaconi/go-klarna"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testingMerchant struct {
+	BaseMerchant
+	pushed   []string
+	pushErr  error
+	rejected bool
+	notified *Notification
+}
+
+func (m *testingMerchant) Push(_ context.Context, orderID string) error {
+	m.pushed = append(m.pushed, orderID)
+
+	return m.pushErr
+}
+
+func (m *testingMerchant) Validate(_ context.Context, order *klarna.CheckoutOrder) (ValidationDecision, error) {
+	if m.rejected {
+		return RejectWithReason("out_of_stock"), nil
+	}
+
+	return Accept(), nil
+}
+
+func (m *testingMerchant) AddressUpdate(
+	_ context.Context,
+	order *klarna.CheckoutOrder,
+) (*klarna.CheckoutOrder, error) {
+	if "US" == order.ShippingAddress.Country {
+		return nil, &Rejection{Type: ErrorTypeUnsupportedShippingAddress, Text: "We do not ship to the US"}
+	}
+	order.OrderAmount += 495
+	order.OrderLines = append(order.OrderLines, &klarna.Line{Type: klarna.ShippingFeeLineType, TotalAmount: 495})
+
+	return order, nil
+}
+
+func (m *testingMerchant) Notification(_ context.Context, n *Notification) error {
+	m.notified = n
+
+	return nil
+}
+
+func callback(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+
+	return rec
+}
+
+func TestHandler_Push(t *testing.T) {
+	assertions := assert.New(t)
+
+	m := &testingMerchant{}
+	h := NewHandler(m, Config{})
+
+	rec := callback(h, http.MethodPost, "/klarna/push?klarna_order_id=abc", "")
+	assertions.Equal(http.StatusOK, rec.Code)
+	assertions.Equal([]string{"abc"}, m.pushed)
+
+	m.pushErr = errors.New("database down")
+	rec = callback(h.Push(), http.MethodPost, "/anything?klarna_order_id=abc", "")
+	assertions.Equal(http.StatusInternalServerError, rec.Code)
+
+	rec = callback(h, http.MethodPost, "/klarna/push", "")
+	assertions.Equal(http.StatusBadRequest, rec.Code)
+
+	rec = callback(h, http.MethodGet, "/klarna/push?klarna_order_id=abc", "")
+	assertions.Equal(http.StatusMethodNotAllowed, rec.Code)
+	assertions.Equal(http.MethodPost, rec.Header().Get("Allow"))
+}
+
+func TestHandler_Validation(t *testing.T) {
+	assertions := assert.New(t)
+
+	m := &testingMerchant{}
+	h := NewHandler(m, Config{ValidationErrorURL: "https://shop.example/checkout/error"})
+
+	rec := callback(h, http.MethodPost, "/klarna/validation", `{"order_id":"abc"}`)
+	assertions.Equal(http.StatusOK, rec.Code)
+
+	m.rejected = true
+	rec = callback(h, http.MethodPost, "/klarna/validation", `{"order_id":"abc"}`)
+	assertions.Equal(http.StatusSeeOther, rec.Code)
+	assertions.Equal("https://shop.example/checkout/error", rec.Header().Get("Location"))
+
+	rec = callback(NewHandler(m, Config{}), http.MethodPost, "/klarna/validation", `{
+		"order_id": "abc",
+		"merchant_urls": {"checkout": "https://shop.example/checkout"}
+	}`)
+	assertions.Equal(http.StatusSeeOther, rec.Code)
+	assertions.Equal("https://shop.example/checkout", rec.Header().Get("Location"))
+
+	rec = callback(h, http.MethodPost, "/klarna/validation", `{"order_id":`)
+	assertions.Equal(http.StatusBadRequest, rec.Code)
+}
+
+func TestHandler_AddressUpdate(t *testing.T) {
+	assertions := assert.New(t)
+
+	h := NewHandler(&testingMerchant{}, Config{})
+
+	rec := callback(h, http.MethodPost, "/klarna/address_update", `{
+		"order_id": "abc",
+		"status": "checkout_incomplete",
+		"purchase_currency": "EUR",
+		"order_amount": 1000,
+		"order_tax_amount": 160,
+		"order_lines": [{"name": "shoe", "quantity": 1, "unit_price": 1000, "total_amount": 1000}],
+		"shipping_address": {"country": "DE"}
+	}`)
+	assertions.Equal(http.StatusOK, rec.Code)
+	assertions.Equal("application/json", rec.Header().Get("Content-Type"))
+
+	var res map[string]interface{}
+	assertions.Nil(json.Unmarshal(rec.Body.Bytes(), &res))
+	assertions.Equal(1495.0, res["order_amount"])
+	assertions.Len(res["order_lines"], 2)
+	assertions.NotContains(res, "order_id")
+	assertions.NotContains(res, "status")
+
+	rec = callback(h.AddressUpdate(), http.MethodPost, "/", `{"shipping_address": {"country": "US"}}`)
+	assertions.Equal(http.StatusBadRequest, rec.Code)
+	assertions.JSONEq(
+		`{"error_type":"unsupported_shipping_address","error_text":"We do not ship to the US"}`,
+		rec.Body.String(),
+	)
+}
+
+func TestHandler_DefaultCallbacks(t *testing.T) {
+	assertions := assert.New(t)
+
+	m := &testingMerchant{}
+	h := NewHandler(m, Config{})
+
+	body := `{"purchase_currency":"EUR","order_amount":1000,"order_tax_amount":0,"order_lines":[]}`
+	for _, target := range []string{"/k/shipping_option_update", "/k/country_change"} {
+		rec := callback(h, http.MethodPost, target, body)
+		assertions.Equal(http.StatusOK, rec.Code, target)
+		assertions.JSONEq(body, rec.Body.String(), target)
+	}
+
+	rec := callback(h, http.MethodPost, "/k/notification", `{"order_id":"abc","event_type":"FRAUD_RISK_STOPPED"}`)
+	assertions.Equal(http.StatusOK, rec.Code)
+	assertions.Equal(&Notification{OrderID: "abc", EventType: FraudRiskStopped}, m.notified)
+
+	rec = callback(h, http.MethodPost, "/k/unknown", "")
+	assertions.Equal(http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,102 @@
+package callbacks
+
+import (
+	"context"
+	klarna "github.com/Flaconi/go-klarna"
+)
+
+const (
+	// ErrorTypeAddress rejects an address Klarna can not ship to, see Rejection
+	ErrorTypeAddress = "address_error"
+	// ErrorTypeUnsupportedShippingAddress rejects a shipping address the merchant does not deliver to
+	ErrorTypeUnsupportedShippingAddress = "unsupported_shipping_address"
+	// ErrorTypeShippingOption rejects a shipping option that is not available for the order
+	ErrorTypeShippingOption = "shipping_option_error"
+
+	// Notification event types sent by Klarna for orders pending a fraud decision
+	FraudRiskAccepted = "FRAUD_RISK_ACCEPTED"
+	FraudRiskRejected = "FRAUD_RISK_REJECTED"
+	FraudRiskStopped  = "FRAUD_RISK_STOPPED"
+)
+
+type (
+	// Merchant type is implemented by the shop handling the Checkout API callbacks, embed BaseMerchant to only
+	// implement the callbacks you need
+	Merchant interface {
+		// Push is called once the order is completed, a returned error makes Klarna push it again later
+		Push(ctx context.Context, orderID string) error
+		// Validate is called right before the order is placed, it decides whether the order is accepted. A returned
+		// error or a missed deadline makes Config.DefaultDecision apply
+		Validate(ctx context.Context, order *klarna.CheckoutOrder) (ValidationDecision, error)
+		// AddressUpdate is called when the customer changes the address, it returns the updated order. A *Rejection
+		// refuses the address
+		AddressUpdate(ctx context.Context, order *klarna.CheckoutOrder) (*klarna.CheckoutOrder, error)
+		// ShippingOptionUpdate is called when the customer picks another shipping option, it returns the updated
+		// order. A *Rejection refuses the shipping option
+		ShippingOptionUpdate(ctx context.Context, order *klarna.CheckoutOrder) (*klarna.CheckoutOrder, error)
+		// CountryChange is called when the customer changes the purchase country, it returns the updated order. A
+		// *Rejection refuses the country
+		CountryChange(ctx context.Context, order *klarna.CheckoutOrder) (*klarna.CheckoutOrder, error)
+		// Notification is called when Klarna took a fraud decision on a pending order
+		Notification(ctx context.Context, n *Notification) error
+	}
+
+	// BaseMerchant type implements every callback of Merchant by accepting whatever Klarna sends, it is meant to be
+	// embedded
+	BaseMerchant struct{}
+
+	// Notification type is the payload of the notification callback
+	Notification struct {
+		OrderID   string `json:"order_id"`
+		EventType string `json:"event_type"`
+	}
+
+	// Rejection type is returned by the update callbacks to refuse the change of the customer, Klarna shows the text
+	// to the customer
+	Rejection struct {
+		Type string `json:"error_type"`
+		Text string `json:"error_text,omitempty"`
+	}
+)
+
+// Error method returns the string representation of the rejection
+func (r *Rejection) Error() string {
+	if "" == r.Text {
+		return "rejected: " + r.Type
+	}
+
+	return "rejected: " + r.Type + ": " + r.Text
+}
+
+// Push method accepts the push
+func (BaseMerchant) Push(context.Context, string) error {
+	return nil
+}
+
+// Validate method accepts the order
+func (BaseMerchant) Validate(context.Context, *klarna.CheckoutOrder) (ValidationDecision, error) {
+	return Accept(), nil
+}
+
+// AddressUpdate method returns the order unchanged
+func (BaseMerchant) AddressUpdate(_ context.Context, order *klarna.CheckoutOrder) (*klarna.CheckoutOrder, error) {
+	return order, nil
+}
+
+// ShippingOptionUpdate method returns the order unchanged
+func (BaseMerchant) ShippingOptionUpdate(
+	_ context.Context,
+	order *klarna.CheckoutOrder,
+) (*klarna.CheckoutOrder, error) {
+	return order, nil
+}
+
+// CountryChange method returns the order unchanged
+func (BaseMerchant) CountryChange(_ context.Context, order *klarna.CheckoutOrder) (*klarna.CheckoutOrder, error) {
+	return order, nil
+}
+
+// Notification method ignores the notification
+func (BaseMerchant) Notification(context.Context, *Notification) error {
+	return nil
+}
@@ -0,0 +1,106 @@
+package callbacks
+
+import (
+	"fmt"
+	klarna "github.com/Flaconi/go-klarna"
+)
+
+// OrderUpdate type helps answering the address_update, shipping_option_update and country_change callbacks with a
+// consistent order: the shipping fee and sales tax lines are swapped and the totals recomputed from the lines
+type OrderUpdate struct {
+	order *klarna.CheckoutOrder
+}
+
+// NewOrderUpdate factory method starts the update of the order received with a callback, the received order itself
+// is left untouched
+func NewOrderUpdate(order *klarna.CheckoutOrder) *OrderUpdate {
+	updated := *order
+	updated.OrderLines = append([]*klarna.Line(nil), order.OrderLines...)
+
+	return &OrderUpdate{order: &updated}
+}
+
+// ShippingFeeLine function builds the shipping fee line of a shipping option, e.g. of the SelectedShippingOption
+// of the order
+func ShippingFeeLine(o *klarna.ShippingOption) *klarna.Line {
+	return &klarna.Line{
+		Type:           klarna.ShippingFeeLineType,
+		Reference:      o.ID,
+		Name:           o.Name,
+		Quantity:       1,
+		UnitPrice:      o.Price,
+		TaxRate:        o.TaxRate,
+		TotalAmount:    o.Price,
+		TotalTaxAmount: o.TaxAmount,
+	}
+}
+
+// SalesTaxLine function builds a sales tax line, as used in the US where taxes are not included in the prices
+func SalesTaxLine(name string, amount int) *klarna.Line {
+	return &klarna.Line{
+		Type:        klarna.SalesTaxLineType,
+		Name:        name,
+		Quantity:    1,
+		UnitPrice:   amount,
+		TotalAmount: amount,
+	}
+}
+
+// SetShippingFee method replaces the shipping fee lines of the order with the given one, nil removes them
+func (u *OrderUpdate) SetShippingFee(fee *klarna.Line) *OrderUpdate {
+	u.replaceLines(klarna.ShippingFeeLineType, fee)
+
+	return u
+}
+
+// SetSalesTax method replaces the sales tax lines of the order with the given ones, none removes them
+func (u *OrderUpdate) SetSalesTax(lines ...*klarna.Line) *OrderUpdate {
+	u.replaceLines(klarna.SalesTaxLineType, lines...)
+
+	return u
+}
+
+// Order method checks the order lines and returns the updated order with its OrderAmount and OrderTaxAmount
+// recomputed from them
+func (u *OrderUpdate) Order() (*klarna.CheckoutOrder, error) {
+	amount, tax := 0, 0
+	for i, l := range u.order.OrderLines {
+		if nil == l {
+			return nil, fmt.Errorf("order line %d is nil", i)
+		}
+		if expected := l.Quantity*l.UnitPrice - l.TotalDiscountAmount; expected != l.TotalAmount {
+			return nil, fmt.Errorf(
+				"order line %d %q: total_amount %d does not match quantity times unit_price minus discount %d",
+				i, l.Name, l.TotalAmount, expected,
+			)
+		}
+
+		amount += l.TotalAmount
+		if klarna.SalesTaxLineType == l.Type {
+			tax += l.TotalAmount
+		} else {
+			tax += l.TotalTaxAmount
+		}
+	}
+
+	u.order.OrderAmount = amount
+	u.order.OrderTaxAmount = tax
+
+	return u.order, nil
+}
+
+// replaceLines method drops the order lines of the given type and appends the given ones
+func (u *OrderUpdate) replaceLines(lineType string, lines ...*klarna.Line) {
+	kept := u.order.OrderLines[:0]
+	for _, l := range u.order.OrderLines {
+		if nil == l || lineType != l.Type {
+			kept = append(kept, l)
+		}
+	}
+	for _, l := range lines {
+		if nil != l {
+			kept = append(kept, l)
+		}
+	}
+	u.order.OrderLines = kept
+}
@@ -0,0 +1,65 @@
+package callbacks
+
+import (
+	klarna "github.com/Flaconi/go-klarna"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestOrderUpdate_ShippingOption(t *testing.T) {
+	assertions := assert.New(t)
+
+	express := &klarna.ShippingOption{ID: "express", Name: "Express", Price: 990, TaxAmount: 158, TaxRate: 1900}
+	received := &klarna.CheckoutOrder{
+		OrderAmount:    2495,
+		OrderTaxAmount: 398,
+		OrderLines: []*klarna.Line{
+			{Type: "physical", Name: "shoe", Quantity: 2, UnitPrice: 1000, TotalAmount: 2000, TotalTaxAmount: 319},
+			{Type: klarna.ShippingFeeLineType, Name: "Standard", Quantity: 1, UnitPrice: 495, TotalAmount: 495,
+				TotalTaxAmount: 79},
+		},
+		SelectedShippingOption: express,
+	}
+
+	order, err := NewOrderUpdate(received).SetShippingFee(ShippingFeeLine(received.SelectedShippingOption)).Order()
+	assertions.Nil(err)
+	assertions.Len(order.OrderLines, 2)
+	assertions.Equal("express", order.OrderLines[1].Reference)
+	assertions.Equal(2990, order.OrderAmount)
+	assertions.Equal(477, order.OrderTaxAmount)
+
+	assertions.Equal(2495, received.OrderAmount)
+	assertions.Equal("Standard", received.OrderLines[1].Name)
+}
+
+func TestOrderUpdate_SalesTax(t *testing.T) {
+	assertions := assert.New(t)
+
+	received := &klarna.CheckoutOrder{
+		OrderLines: []*klarna.Line{
+			{Type: "physical", Name: "shoe", Quantity: 1, UnitPrice: 5000, TotalAmount: 5000},
+			SalesTaxLine("Sales tax NY", 400),
+		},
+	}
+
+	order, err := NewOrderUpdate(received).
+		SetShippingFee(nil).
+		SetSalesTax(SalesTaxLine("Sales tax CA", 363), SalesTaxLine("County tax", 50)).
+		Order()
+	assertions.Nil(err)
+	assertions.Len(order.OrderLines, 3)
+	assertions.Equal(5413, order.OrderAmount)
+	assertions.Equal(413, order.OrderTaxAmount)
+}
+
+func TestOrderUpdate_InconsistentLine(t *testing.T) {
+	assertions := assert.New(t)
+
+	_, err := NewOrderUpdate(&klarna.CheckoutOrder{
+		OrderLines: []*klarna.Line{{Name: "shoe", Quantity: 2, UnitPrice: 1000, TotalAmount: 1000}},
+	}).Order()
+	assertions.EqualError(
+		err,
+		`order line 0 "shoe": total_amount 1000 does not match quantity times unit_price minus discount 2000`,
+	)
+}
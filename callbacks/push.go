@@ -0,0 +1,276 @@
+package callbacks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	klarna "github.com/Flaconi/go-klarna"
+	"sync"
+	"time"
+)
+
+// DefaultDedupeTTL covers the 48 hours Klarna keeps pushing an order that is not acknowledged
+const DefaultDedupeTTL = 48 * time.Hour
+
+const (
+	// ClaimAcquired tells the caller holds the claim and completes the order
+	ClaimAcquired ClaimResult = iota
+	// ClaimInProgress tells another caller holds the claim and is completing the order
+	ClaimInProgress
+	// ClaimCompleted tells the order was already completed
+	ClaimCompleted
+)
+
+// ErrClaimInProgress error describes a push of an order another instance is completing, Klarna pushes it again later
+var ErrClaimInProgress = errors.New("order is being completed by another instance")
+
+type (
+	// ClaimResult type is the outcome of DedupeStore.Claim
+	ClaimResult int
+
+	// OrderCompleter type is implemented by the shop handling the completed orders, e.g. creating the shipment
+	OrderCompleter interface {
+		// OnOrderCompleted is called once per order with the order fetched from the Order Management API, a returned
+		// error makes Klarna push it again later
+		OnOrderCompleted(ctx context.Context, order *klarna.OrderManagementOrder) error
+	}
+
+	// OrderCompleterFunc type is an adapter to use an ordinary function as OrderCompleter
+	OrderCompleterFunc func(ctx context.Context, order *klarna.OrderManagementOrder) error
+
+	// DedupeStore type records the orders claimed and completed, so an order is completed once even when its pushes
+	// reach several instances of the shop at the same time. Share a persistent implementation between the instances,
+	// e.g. claiming with Redis SET NX
+	DedupeStore interface {
+		// Claim atomically claims the completion of the order unless it is claimed or completed already. The claims
+		// of an instance that crashed while completing should expire, so the order is completed by the next push
+		Claim(ctx context.Context, orderID string) (ClaimResult, error)
+		// Mark records the claimed order as completed
+		Mark(ctx context.Context, orderID string) error
+		// Release drops the claim of an order whose completion failed, so the next push completes it
+		Release(ctx context.Context, orderID string) error
+	}
+
+	// MemoryDedupeStore type is the in-memory DedupeStore, it only suppresses the duplicates within the process
+	MemoryDedupeStore struct {
+		ttl    time.Duration
+		mu     sync.Mutex
+		claims map[string]claim
+	}
+
+	// claim type is an order claimed in the MemoryDedupeStore
+	claim struct {
+		at        time.Time
+		completed bool
+	}
+
+	// MarkError type is returned by PushProcessor.Push when the order was completed and acknowledged but could not be
+	// marked in the DedupeStore. The processor remembers the order itself, so it is not completed again by this
+	// process. The Handler logs it and answers the push with 200
+	MarkError struct {
+		OrderID string
+		Err     error
+	}
+
+	// PushProcessor type handles the push callback: it fetches the order, hands it to the OrderCompleter once and
+	// acknowledges it so Klarna stops pushing. Set it as Config.PushProcessor of the Handler
+	PushProcessor struct {
+		orders    klarna.OrderManagementContextSrv
+		completer OrderCompleter
+		store     DedupeStore
+		// unmarked holds the orders completed but not marked in the store
+		unmarked *MemoryDedupeStore
+
+		mu    sync.Mutex
+		locks map[string]*orderLock
+	}
+
+	// orderLock type serializes the concurrent pushes of an order
+	orderLock struct {
+		sync.Mutex
+		refs int
+	}
+)
+
+// Error method returns the string representation of the error
+func (e *MarkError) Error() string {
+	return fmt.Sprintf("marking order %s completed: %s", e.OrderID, e.Err)
+}
+
+// Unwrap method returns the error of the DedupeStore
+func (e *MarkError) Unwrap() error {
+	return e.Err
+}
+
+// OnOrderCompleted method calls f(ctx, order)
+func (f OrderCompleterFunc) OnOrderCompleted(ctx context.Context, order *klarna.OrderManagementOrder) error {
+	return f(ctx, order)
+}
+
+// NewMemoryDedupeStore factory method, the claims and orders are forgotten after the ttl, 0 keeps them forever
+func NewMemoryDedupeStore(ttl time.Duration) *MemoryDedupeStore {
+	return &MemoryDedupeStore{ttl: ttl, claims: make(map[string]claim)}
+}
+
+// Claim method claims the order unless it was claimed or marked within the ttl, dropping the expired ones
+func (s *MemoryDedupeStore) Claim(_ context.Context, orderID string) (ClaimResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.dropExpired(now)
+	if c, ok := s.claims[orderID]; ok {
+		if c.completed {
+			return ClaimCompleted, nil
+		}
+		return ClaimInProgress, nil
+	}
+	s.claims[orderID] = claim{at: now}
+
+	return ClaimAcquired, nil
+}
+
+// Mark method records the order as completed, dropping the expired ones
+func (s *MemoryDedupeStore) Mark(_ context.Context, orderID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.dropExpired(now)
+	s.claims[orderID] = claim{at: now, completed: true}
+
+	return nil
+}
+
+// Release method drops the claim of the order unless it is completed
+func (s *MemoryDedupeStore) Release(_ context.Context, orderID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.claims[orderID]; ok && !c.completed {
+		delete(s.claims, orderID)
+	}
+
+	return nil
+}
+
+// completed method tells whether the order was marked within the ttl
+func (s *MemoryDedupeStore) completed(orderID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.claims[orderID]
+
+	return ok && c.completed && !s.expired(c.at, time.Now())
+}
+
+// dropExpired method forgets the expired claims and orders, the lock must be held
+func (s *MemoryDedupeStore) dropExpired(now time.Time) {
+	for id, c := range s.claims {
+		if s.expired(c.at, now) {
+			delete(s.claims, id)
+		}
+	}
+}
+
+// expired method tells whether an order marked at the given time is forgotten
+func (s *MemoryDedupeStore) expired(at, now time.Time) bool {
+	return 0 < s.ttl && now.Sub(at) >= s.ttl
+}
+
+// NewPushProcessor factory method, a nil store defaults to a MemoryDedupeStore keeping the orders DefaultDedupeTTL
+func NewPushProcessor(
+	orders klarna.OrderManagementContextSrv,
+	completer OrderCompleter,
+	store DedupeStore,
+) *PushProcessor {
+	if nil == store {
+		store = NewMemoryDedupeStore(DefaultDedupeTTL)
+	}
+
+	return &PushProcessor{
+		orders:    orders,
+		completer: completer,
+		store:     store,
+		unmarked:  NewMemoryDedupeStore(DefaultDedupeTTL),
+		locks:     make(map[string]*orderLock),
+	}
+}
+
+// Push method processes the push of the order. An order already completed is only acknowledged again, as the
+// previous acknowledge may have failed. An order claimed by another instance fails with ErrClaimInProgress. A
+// returned error other than *MarkError makes Klarna push the order again
+func (p *PushProcessor) Push(ctx context.Context, orderID string) error {
+	unlock := p.lock(orderID)
+	defer unlock()
+
+	var markErr error
+	if !p.unmarked.completed(orderID) {
+		result, err := p.store.Claim(ctx, orderID)
+		if nil != err {
+			return fmt.Errorf("claiming push of order %s: %w", orderID, err)
+		}
+		switch result {
+		case ClaimInProgress:
+			return fmt.Errorf("pushing order %s: %w", orderID, ErrClaimInProgress)
+		case ClaimAcquired:
+			if err = p.complete(ctx, orderID); nil != err {
+				return err
+			}
+			if err = p.store.Mark(ctx, orderID); nil != err {
+				// the order must not be completed twice, acknowledge it anyway so Klarna stops pushing
+				_ = p.unmarked.Mark(ctx, orderID)
+				markErr = &MarkError{OrderID: orderID, Err: err}
+			}
+		}
+	}
+
+	if err := p.orders.AcknowledgeOrderContext(ctx, orderID); nil != err {
+		return fmt.Errorf("acknowledging order %s: %w", orderID, err)
+	}
+
+	return markErr
+}
+
+// complete method hands the claimed order to the OrderCompleter, the claim is released when that fails
+func (p *PushProcessor) complete(ctx context.Context, orderID string) error {
+	order, err := p.orders.GetOrderContext(ctx, orderID)
+	if nil != err {
+		err = fmt.Errorf("fetching order %s: %w", orderID, err)
+	} else if err = p.completer.OnOrderCompleted(ctx, order); nil != err {
+		err = fmt.Errorf("completing order %s: %w", orderID, err)
+	}
+	if nil == err {
+		return nil
+	}
+
+	if releaseErr := p.store.Release(ctx, orderID); nil != releaseErr {
+		err = errors.Join(err, fmt.Errorf("releasing claim of order %s: %w", orderID, releaseErr))
+	}
+
+	return err
+}
+
+// lock method serializes the pushes of the order within the process and returns the function releasing the lock
+func (p *PushProcessor) lock(orderID string) func() {
+	p.mu.Lock()
+	l, ok := p.locks[orderID]
+	if !ok {
+		l = &orderLock{}
+		p.locks[orderID] = l
+	}
+	l.refs++
+	p.mu.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+
+		p.mu.Lock()
+		if l.refs--; 0 == l.refs {
+			delete(p.locks, orderID)
+		}
+		p.mu.Unlock()
+	}
+}
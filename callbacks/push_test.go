@@ -0,0 +1,253 @@
+package callbacks
+
+import (
+	"context"
+	"errors"
+	klarna "github.com/Flaconi/go-klarna"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testingOrders struct {
+	klarna.OrderManagementContextSrv
+	mu           sync.Mutex
+	fetched      int
+	acknowledged int
+	ackErr       error
+}
+
+func (o *testingOrders) GetOrderContext(_ context.Context, id string) (*klarna.OrderManagementOrder, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.fetched++
+
+	return &klarna.OrderManagementOrder{ID: id, Status: "AUTHORIZED"}, nil
+}
+
+func (o *testingOrders) AcknowledgeOrderContext(context.Context, string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.acknowledged++
+
+	return o.ackErr
+}
+
+func TestPushProcessor_Push(t *testing.T) {
+	assertions := assert.New(t)
+
+	orders := &testingOrders{}
+	var completed []string
+	p := NewPushProcessor(orders, OrderCompleterFunc(func(_ context.Context, o *klarna.OrderManagementOrder) error {
+		completed = append(completed, o.ID)
+		return nil
+	}), nil)
+
+	assertions.Nil(p.Push(context.Background(), "abc"))
+	assertions.Nil(p.Push(context.Background(), "abc"))
+	assertions.Equal([]string{"abc"}, completed)
+	assertions.Equal(1, orders.fetched)
+	assertions.Equal(2, orders.acknowledged)
+}
+
+func TestPushProcessor_Failures(t *testing.T) {
+	assertions := assert.New(t)
+
+	orders := &testingOrders{ackErr: errors.New("service unavailable")}
+	calls := 0
+	failing := true
+	p := NewPushProcessor(orders, OrderCompleterFunc(func(context.Context, *klarna.OrderManagementOrder) error {
+		calls++
+		if failing {
+			return errors.New("warehouse down")
+		}
+		return nil
+	}), NewMemoryDedupeStore(0))
+
+	err := p.Push(context.Background(), "abc")
+	assertions.EqualError(err, "completing order abc: warehouse down")
+	assertions.Equal(0, orders.acknowledged)
+
+	failing = false
+	err = p.Push(context.Background(), "abc")
+	assertions.EqualError(err, "acknowledging order abc: service unavailable")
+
+	orders.ackErr = nil
+	assertions.Nil(p.Push(context.Background(), "abc"))
+	assertions.Equal(2, calls)
+	assertions.Equal(2, orders.acknowledged)
+}
+
+type failingStore struct {
+	marks int
+}
+
+func (s *failingStore) Claim(context.Context, string) (ClaimResult, error) {
+	return ClaimAcquired, nil
+}
+
+func (s *failingStore) Mark(context.Context, string) error {
+	s.marks++
+
+	return errors.New("redis down")
+}
+
+func (s *failingStore) Release(context.Context, string) error {
+	return nil
+}
+
+func TestPushProcessor_MarkFailure(t *testing.T) {
+	assertions := assert.New(t)
+
+	orders := &testingOrders{ackErr: errors.New("service unavailable")}
+	store := &failingStore{}
+	calls := 0
+	p := NewPushProcessor(orders, OrderCompleterFunc(func(context.Context, *klarna.OrderManagementOrder) error {
+		calls++
+		return nil
+	}), store)
+
+	err := p.Push(context.Background(), "abc")
+	assertions.EqualError(err, "acknowledging order abc: service unavailable")
+
+	orders.ackErr = nil
+	assertions.Nil(p.Push(context.Background(), "abc"))
+	assertions.Equal(1, calls)
+	assertions.Equal(1, store.marks)
+	assertions.Equal(2, orders.acknowledged)
+
+	err = p.Push(context.Background(), "def")
+	var markErr *MarkError
+	assertions.ErrorAs(err, &markErr)
+	assertions.Equal("def", markErr.OrderID)
+	assertions.EqualError(err, "marking order def completed: redis down")
+	assertions.Equal(3, orders.acknowledged)
+
+	h := NewHandler(&testingMerchant{}, Config{PushProcessor: p})
+	rec := callback(h, http.MethodPost, "/klarna/push?klarna_order_id=ghi", "")
+	assertions.Equal(http.StatusOK, rec.Code)
+	rec = callback(h, http.MethodPost, "/klarna/push?klarna_order_id=ghi", "")
+	assertions.Equal(http.StatusOK, rec.Code)
+	assertions.Equal(3, calls)
+}
+
+func TestPushProcessor_Concurrent(t *testing.T) {
+	assertions := assert.New(t)
+
+	var mu sync.Mutex
+	calls := 0
+	p := NewPushProcessor(&testingOrders{}, OrderCompleterFunc(func(context.Context, *klarna.OrderManagementOrder) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return nil
+	}), nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assertions.Nil(p.Push(context.Background(), "abc"))
+		}()
+	}
+	wg.Wait()
+
+	assertions.Equal(1, calls)
+	assertions.Empty(p.locks)
+}
+
+func TestPushProcessor_SharedStore(t *testing.T) {
+	assertions := assert.New(t)
+
+	store := NewMemoryDedupeStore(0)
+	started, release := make(chan struct{}), make(chan struct{})
+	calls := 0
+	blocking := OrderCompleterFunc(func(context.Context, *klarna.OrderManagementOrder) error {
+		calls++
+		close(started)
+		<-release
+		return nil
+	})
+	failing := OrderCompleterFunc(func(context.Context, *klarna.OrderManagementOrder) error {
+		return errors.New("completed twice")
+	})
+	first := NewPushProcessor(&testingOrders{}, blocking, store)
+	second := NewPushProcessor(&testingOrders{}, failing, store)
+
+	done := make(chan error)
+	go func() {
+		done <- first.Push(context.Background(), "abc")
+	}()
+	<-started
+
+	assertions.ErrorIs(second.Push(context.Background(), "abc"), ErrClaimInProgress)
+	h := NewHandler(&testingMerchant{}, Config{PushProcessor: second})
+	rec := callback(h, http.MethodPost, "/klarna/push?klarna_order_id=abc", "")
+	assertions.Equal(http.StatusInternalServerError, rec.Code)
+
+	close(release)
+	assertions.Nil(<-done)
+	assertions.Nil(second.Push(context.Background(), "abc"))
+	assertions.Equal(1, calls)
+}
+
+func TestMemoryDedupeStore_Claim(t *testing.T) {
+	assertions := assert.New(t)
+	ctx := context.Background()
+
+	s := NewMemoryDedupeStore(time.Hour)
+	result, err := s.Claim(ctx, "abc")
+	assertions.Nil(err)
+	assertions.Equal(ClaimAcquired, result)
+	result, _ = s.Claim(ctx, "abc")
+	assertions.Equal(ClaimInProgress, result)
+
+	assertions.Nil(s.Release(ctx, "abc"))
+	result, _ = s.Claim(ctx, "abc")
+	assertions.Equal(ClaimAcquired, result)
+
+	assertions.Nil(s.Mark(ctx, "abc"))
+	assertions.Nil(s.Release(ctx, "abc"))
+	result, _ = s.Claim(ctx, "abc")
+	assertions.Equal(ClaimCompleted, result)
+}
+
+func TestMemoryDedupeStore_TTL(t *testing.T) {
+	assertions := assert.New(t)
+	ctx := context.Background()
+
+	s := NewMemoryDedupeStore(time.Hour)
+	assertions.Nil(s.Mark(ctx, "abc"))
+	assertions.True(s.completed("abc"))
+
+	s.claims["abc"] = claim{at: time.Now().Add(-2 * time.Hour), completed: true}
+	assertions.False(s.completed("abc"))
+	result, _ := s.Claim(ctx, "abc")
+	assertions.Equal(ClaimAcquired, result)
+
+	s.claims["abc"] = claim{at: time.Now().Add(-2 * time.Hour)}
+	assertions.Nil(s.Mark(ctx, "def"))
+	assertions.NotContains(s.claims, "abc")
+}
+
+func TestHandler_PushProcessor(t *testing.T) {
+	assertions := assert.New(t)
+
+	m := &testingMerchant{}
+	orders := &testingOrders{}
+	h := NewHandler(m, Config{PushProcessor: NewPushProcessor(orders, OrderCompleterFunc(
+		func(context.Context, *klarna.OrderManagementOrder) error { return nil },
+	), nil)})
+
+	rec := callback(h, http.MethodPost, "/klarna/push?klarna_order_id=abc", "")
+	assertions.Equal(http.StatusOK, rec.Code)
+	assertions.Empty(m.pushed)
+	assertions.Equal(1, orders.acknowledged)
+
+	orders.ackErr = errors.New("service unavailable")
+	rec = callback(h, http.MethodPost, "/klarna/push?klarna_order_id=abc", "")
+	assertions.Equal(http.StatusInternalServerError, rec.Code)
+}
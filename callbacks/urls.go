@@ -0,0 +1,55 @@
+package callbacks
+
+import (
+	"fmt"
+	klarna "github.com/Flaconi/go-klarna"
+	"net/url"
+	"strings"
+)
+
+// OrderIDPlaceholder is replaced by Klarna with the id of the order when calling a merchant URL
+const OrderIDPlaceholder = "{checkout.order.id}"
+
+// Pages type holds the merchant pages the checkout links to, they are served by the shop itself
+type Pages struct {
+	Terms        string
+	Checkout     string
+	Confirmation string
+}
+
+// MerchantURLs function builds the merchant URLs of a checkout order, pointing every callback at the Handler served
+// at baseURL. Klarna requires the callbacks to be served over https
+func MerchantURLs(baseURL string, pages Pages) (*klarna.CheckoutMerchantURLS, error) {
+	base, err := callbackBase(baseURL)
+	if nil != err {
+		return nil, err
+	}
+
+	return &klarna.CheckoutMerchantURLS{
+		Terms:                pages.Terms,
+		Checkout:             pages.Checkout,
+		Confirmation:         pages.Confirmation,
+		Push:                 base + PushPath + "?" + OrderIDParam + "=" + OrderIDPlaceholder,
+		Validation:           base + ValidationPath,
+		ShippingOptionUpdate: base + ShippingOptionUpdatePath,
+		AddressUpdate:        base + AddressUpdatePath,
+		Notification:         base + NotificationPath,
+		CountryChange:        base + CountryChangePath,
+	}, nil
+}
+
+// callbackBase function checks the base URL of the callbacks and returns it without a trailing slash
+func callbackBase(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if nil != err {
+		return "", fmt.Errorf("invalid callback base URL: %w", err)
+	}
+	if "https" != u.Scheme || "" == u.Host {
+		return "", fmt.Errorf("callback base URL %q must be an absolute https URL", baseURL)
+	}
+	if "" != u.RawQuery || "" != u.Fragment {
+		return "", fmt.Errorf("callback base URL %q must not have a query or fragment", baseURL)
+	}
+
+	return strings.TrimRight(baseURL, "/"), nil
+}
@@ -0,0 +1,30 @@
+package callbacks
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMerchantURLs(t *testing.T) {
+	assertions := assert.New(t)
+
+	urls, err := MerchantURLs("https://shop.example/klarna/", Pages{
+		Terms:        "https://shop.example/terms",
+		Checkout:     "https://shop.example/checkout",
+		Confirmation: "https://shop.example/confirmation?order_id={checkout.order.id}",
+	})
+	assertions.Nil(err)
+	assertions.Equal("https://shop.example/terms", urls.Terms)
+	assertions.Equal("https://shop.example/klarna/push?klarna_order_id={checkout.order.id}", urls.Push)
+	assertions.Equal("https://shop.example/klarna/validation", urls.Validation)
+	assertions.Equal("https://shop.example/klarna/address_update", urls.AddressUpdate)
+	assertions.Equal("https://shop.example/klarna/shipping_option_update", urls.ShippingOptionUpdate)
+	assertions.Equal("https://shop.example/klarna/country_change", urls.CountryChange)
+	assertions.Equal("https://shop.example/klarna/notification", urls.Notification)
+
+	_, err = MerchantURLs("http://shop.example/klarna", Pages{})
+	assertions.EqualError(err, `callback base URL "http://shop.example/klarna" must be an absolute https URL`)
+
+	_, err = MerchantURLs("https://shop.example/klarna?x=1", Pages{})
+	assertions.NotNil(err)
+}
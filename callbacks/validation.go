@@ -0,0 +1,159 @@
+package callbacks
+
+import (
+	"context"
+	"errors"
+	klarna "github.com/Flaconi/go-klarna"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultValidationTimeout leaves a safety margin to the 10 seconds Klarna waits for the validation callback
+	DefaultValidationTimeout = 8 * time.Second
+
+	// placeholders of the error page templates, see Config.ErrorPages
+	reasonPlaceholder  = "{reason}"
+	orderIDPlaceholder = "{order_id}"
+)
+
+// ValidationDecision type is the answer of the merchant to the validation callback, its zero value accepts the order
+type ValidationDecision struct {
+	reject      bool
+	redirectURL string
+	reason      string
+}
+
+// Accept function returns the decision accepting the order
+func Accept() ValidationDecision {
+	return ValidationDecision{}
+}
+
+// RejectWithRedirect function returns the decision rejecting the order and sending the customer to the given page
+func RejectWithRedirect(url string) ValidationDecision {
+	return ValidationDecision{reject: true, redirectURL: url}
+}
+
+// RejectWithReason function returns the decision rejecting the order for the given reason, e.g. "out_of_stock", the
+// customer is sent to the error page of the reason, see Config.ErrorPages
+func RejectWithReason(reason string) ValidationDecision {
+	return ValidationDecision{reject: true, reason: reason}
+}
+
+// Accepted method tells whether the decision accepts the order
+func (d ValidationDecision) Accepted() bool {
+	return !d.reject
+}
+
+// Reason method returns the reason the order is rejected for, if any
+func (d ValidationDecision) Reason() string {
+	return d.reason
+}
+
+// location method resolves the page the customer is redirected to: the redirect of the decision, the error page of
+// the reason, the ValidationErrorURL and last the checkout page of the order. It is empty only for an order without
+// checkout page, which Klarna does not create
+func (d ValidationDecision) location(c Config, order *klarna.CheckoutOrder) string {
+	if "" != d.redirectURL {
+		return d.redirectURL
+	}
+
+	page, ok := c.ErrorPages[d.reason]
+	if !ok {
+		page = c.ValidationErrorURL
+	}
+	if "" == page {
+		if nil == order.MerchantURLS {
+			return ""
+		}
+		return strings.ReplaceAll(order.MerchantURLS.Checkout, OrderIDPlaceholder, url.QueryEscape(order.ID))
+	}
+
+	return strings.NewReplacer(
+		reasonPlaceholder, url.QueryEscape(d.reason),
+		orderIDPlaceholder, url.QueryEscape(order.ID),
+	).Replace(page)
+}
+
+// serveValidation method answers 200 to accept the order, or redirects to an error page with 303 to reject it. The
+// verification of the callback and the decision of the merchant have to complete within Config.ValidationTimeout,
+// Config.DefaultDecision applies otherwise
+func (h *Handler) serveValidation(w http.ResponseWriter, r *http.Request) {
+	order, ok := decode[klarna.CheckoutOrder](w, r)
+	if !ok {
+		return
+	}
+
+	timeout := h.config.ValidationTimeout
+	if 0 >= timeout {
+		timeout = DefaultValidationTimeout
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	decision, order, err := h.decide(ctx, r, order)
+	var verificationErr *VerificationError
+	if errors.As(err, &verificationErr) {
+		h.forbid(w, r, "validation", verificationErr)
+		return
+	}
+	if decision.Accepted() {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	location := decision.location(h.config, order)
+	if "" == location {
+		// only a 303 rejects the order, this is the best that can be done for an order without checkout page
+		h.fail(w, r, "validation", errors.New("order rejected but there is no page to redirect the customer to"))
+		return
+	}
+	http.Redirect(w, r, location, http.StatusSeeOther)
+}
+
+// decide method verifies the callback and asks the merchant for the validation decision, along with the order it
+// was taken on. The default decision applies when the order can not be fetched, the merchant fails or the deadline
+// of the context passes. The returned error is a *VerificationError when the callback failed the verification
+func (h *Handler) decide(
+	ctx context.Context,
+	r *http.Request,
+	order *klarna.CheckoutOrder,
+) (ValidationDecision, *klarna.CheckoutOrder, error) {
+	type answer struct {
+		decision ValidationDecision
+		order    *klarna.CheckoutOrder
+		err      error
+	}
+	answers := make(chan answer, 1)
+	go func() {
+		validated := order
+		fetched, err := h.verify(ctx, r, order.ID)
+		if nil != err {
+			answers <- answer{err: err}
+			return
+		}
+		if nil != fetched {
+			validated = fetched
+		}
+		decision, err := h.merchant.Validate(ctx, validated)
+		answers <- answer{decision, validated, err}
+	}()
+
+	select {
+	case a := <-answers:
+		var verificationErr *VerificationError
+		if errors.As(a.err, &verificationErr) {
+			return ValidationDecision{}, order, a.err
+		}
+		if nil == a.err {
+			return a.decision, a.order, nil
+		}
+		h.log(r, "validation", a.err)
+	case <-ctx.Done():
+		h.log(r, "validation", errors.New("no decision before the deadline, the default decision applies"))
+	}
+
+	return h.config.DefaultDecision, order, nil
+}
@@ -0,0 +1,94 @@
+package callbacks
+
+import (
+	"context"
+	"errors"
+	klarna "github.com/Flaconi/go-klarna"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type decidingMerchant struct {
+	BaseMerchant
+	decision ValidationDecision
+	err      error
+	delay    time.Duration
+}
+
+func (m *decidingMerchant) Validate(ctx context.Context, _ *klarna.CheckoutOrder) (ValidationDecision, error) {
+	select {
+	case <-ctx.Done():
+		return Accept(), ctx.Err()
+	case <-time.After(m.delay):
+	}
+
+	return m.decision, m.err
+}
+
+func TestHandler_ValidationDecisions(t *testing.T) {
+	assertions := assert.New(t)
+
+	m := &decidingMerchant{}
+	h := NewHandler(m, Config{
+		ValidationErrorURL: "https://shop.example/checkout/error?reason={reason}&order={order_id}",
+		ErrorPages: map[string]string{
+			"out_of_stock": "https://shop.example/checkout/sold-out?order={order_id}",
+		},
+	})
+	order := `{"order_id":"a b"}`
+
+	rec := callback(h, http.MethodPost, "/klarna/validation", order)
+	assertions.Equal(http.StatusOK, rec.Code)
+
+	m.decision = RejectWithRedirect("https://shop.example/cart")
+	rec = callback(h, http.MethodPost, "/klarna/validation", order)
+	assertions.Equal(http.StatusSeeOther, rec.Code)
+	assertions.Equal("https://shop.example/cart", rec.Header().Get("Location"))
+
+	m.decision = RejectWithReason("out_of_stock")
+	rec = callback(h, http.MethodPost, "/klarna/validation", order)
+	assertions.Equal(http.StatusSeeOther, rec.Code)
+	assertions.Equal("https://shop.example/checkout/sold-out?order=a+b", rec.Header().Get("Location"))
+
+	m.decision = RejectWithReason("fraud suspected")
+	rec = callback(h, http.MethodPost, "/klarna/validation", order)
+	assertions.Equal(
+		"https://shop.example/checkout/error?reason=fraud+suspected&order=a+b",
+		rec.Header().Get("Location"),
+	)
+
+	noPages := NewHandler(m, Config{})
+	rec = callback(noPages, http.MethodPost, "/klarna/validation", `{
+		"order_id": "a b",
+		"merchant_urls": {"checkout": "https://shop.example/checkout?order={checkout.order.id}"}
+	}`)
+	assertions.Equal(http.StatusSeeOther, rec.Code)
+	assertions.Equal("https://shop.example/checkout?order=a+b", rec.Header().Get("Location"))
+}
+
+func TestHandler_ValidationDeadline(t *testing.T) {
+	assertions := assert.New(t)
+
+	m := &decidingMerchant{decision: Accept(), delay: time.Second}
+	h := NewHandler(m, Config{
+		ValidationTimeout: 20 * time.Millisecond,
+		DefaultDecision:   RejectWithRedirect("https://shop.example/checkout/retry"),
+	})
+
+	start := time.Now()
+	rec := callback(h, http.MethodPost, "/klarna/validation", `{"order_id":"abc"}`)
+	assertions.Less(time.Since(start), 500*time.Millisecond)
+	assertions.Equal(http.StatusSeeOther, rec.Code)
+	assertions.Equal("https://shop.example/checkout/retry", rec.Header().Get("Location"))
+
+	failing := &decidingMerchant{err: errors.New("stock service unavailable")}
+	h = NewHandler(failing, Config{DefaultDecision: RejectWithRedirect("https://shop.example/checkout/retry")})
+	rec = callback(h, http.MethodPost, "/klarna/validation", `{"order_id":"abc"}`)
+	assertions.Equal(http.StatusSeeOther, rec.Code)
+
+	h = NewHandler(&decidingMerchant{delay: time.Second}, Config{ValidationTimeout: 20 * time.Millisecond})
+	rec = callback(h.Validation(), http.MethodPost, "/", `{"order_id":"abc"}`)
+	assertions.Equal(http.StatusOK, rec.Code)
+}
@@ -0,0 +1,182 @@
+package callbacks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	klarna "github.com/Flaconi/go-klarna"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// TokenParam is the query parameter of the callback URLs carrying the token signed with Config.Secret
+	TokenParam = "klarna_token"
+
+	// nonceSize is the amount of random bytes a token is made of
+	nonceSize = 16
+)
+
+var (
+	// ErrMissingToken error describes a callback received without token
+	ErrMissingToken = errors.New("callback token is missing")
+	// ErrInvalidToken error describes a callback token that is not signed with Config.Secret
+	ErrInvalidToken = errors.New("callback token is invalid")
+	// ErrUnknownOrder error describes a callback for an order Klarna does not know
+	ErrUnknownOrder = errors.New("order is unknown to Klarna")
+	// ErrForeignToken error describes a callback token that is not the one the order was created with
+	ErrForeignToken = errors.New("callback token does not belong to the order")
+)
+
+// VerificationError type is the error of a callback that failed the authenticity verification, it is answered with
+// 403. Its Err is one of ErrMissingToken, ErrInvalidToken, ErrUnknownOrder and ErrForeignToken
+type VerificationError struct {
+	Callback string
+	OrderID  string
+	Err      error
+}
+
+// Error method returns the string representation of the error
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("%s callback for order %q failed verification: %s", e.Callback, e.OrderID, e.Err)
+}
+
+// Unwrap method returns the reason of the failed verification
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// MerchantURLs method builds the merchant URLs of a checkout order like the MerchantURLs function does, and signs
+// every callback URL with a fresh token when Config.Secret is set. Create each checkout order with its own URLs, the
+// token then identifies the order when Config.Orders is set
+func (h *Handler) MerchantURLs(baseURL string, pages Pages) (*klarna.CheckoutMerchantURLS, error) {
+	urls, err := MerchantURLs(baseURL, pages)
+	if nil != err || 0 == len(h.config.Secret) {
+		return urls, err
+	}
+
+	token, err := h.newToken()
+	if nil != err {
+		return nil, err
+	}
+	for _, u := range []*string{
+		&urls.Push,
+		&urls.Validation,
+		&urls.ShippingOptionUpdate,
+		&urls.AddressUpdate,
+		&urls.Notification,
+		&urls.CountryChange,
+	} {
+		separator := "?"
+		if strings.Contains(*u, "?") {
+			separator = "&"
+		}
+		*u += separator + TokenParam + "=" + token
+	}
+
+	return urls, nil
+}
+
+// authenticate method verifies the callback when Config.Secret is set, and re-fetches the order from Klarna when
+// Config.Orders is set. The fetched order, if any, is returned; a callback failing the verification is answered with
+// 403 and one failing to fetch the order with 500
+func (h *Handler) authenticate(
+	w http.ResponseWriter,
+	r *http.Request,
+	callback, orderID string,
+) (*klarna.CheckoutOrder, bool) {
+	order, err := h.verify(r.Context(), r, orderID)
+	if nil == err {
+		return order, true
+	}
+
+	var verificationErr *VerificationError
+	if errors.As(err, &verificationErr) {
+		h.forbid(w, r, callback, verificationErr)
+		return nil, false
+	}
+	h.fail(w, r, callback, err)
+
+	return nil, false
+}
+
+// forbid method answers a callback that failed the verification with 403
+func (h *Handler) forbid(w http.ResponseWriter, r *http.Request, callback string, err *VerificationError) {
+	err.Callback = callback
+	h.log(r, callback, err)
+	http.Error(w, err.Err.Error(), http.StatusForbidden)
+}
+
+// verify method checks the token of the callback and that the order was created with it
+func (h *Handler) verify(ctx context.Context, r *http.Request, orderID string) (*klarna.CheckoutOrder, error) {
+	token := r.URL.Query().Get(TokenParam)
+	if 0 != len(h.config.Secret) {
+		if "" == token {
+			return nil, &VerificationError{OrderID: orderID, Err: ErrMissingToken}
+		}
+		if !h.validToken(token) {
+			return nil, &VerificationError{OrderID: orderID, Err: ErrInvalidToken}
+		}
+	}
+
+	if nil == h.config.Orders {
+		return nil, nil
+	}
+
+	order, err := h.config.Orders.RetrieveOrderContext(ctx, orderID)
+	if errors.Is(err, klarna.ErrOrderNotFound) {
+		return nil, &VerificationError{OrderID: orderID, Err: ErrUnknownOrder}
+	}
+	if nil != err {
+		return nil, fmt.Errorf("fetching order %s: %w", orderID, err)
+	}
+	if 0 != len(h.config.Secret) && (nil == order.MerchantURLS || token != tokenOf(order.MerchantURLS.Push)) {
+		return nil, &VerificationError{OrderID: orderID, Err: ErrForeignToken}
+	}
+
+	return order, nil
+}
+
+// newToken method returns a random nonce followed by its signature
+func (h *Handler) newToken() (string, error) {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); nil != err {
+		return "", fmt.Errorf("generating callback token: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(nonce)
+
+	return encoded + "." + h.sign(encoded), nil
+}
+
+// validToken method tells whether the token is signed with the secret
+func (h *Handler) validToken(token string) bool {
+	nonce, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(h.sign(nonce)))
+}
+
+// sign method returns the HMAC-SHA256 signature of the nonce
+func (h *Handler) sign(nonce string) string {
+	mac := hmac.New(sha256.New, h.config.Secret)
+	mac.Write([]byte(nonce))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// tokenOf function returns the token of a callback URL, empty when there is none
+func tokenOf(callbackURL string) string {
+	u, err := url.Parse(callbackURL)
+	if nil != err {
+		return ""
+	}
+
+	return u.Query().Get(TokenParam)
+}
@@ -0,0 +1,175 @@
+package callbacks
+
+import (
+	"context"
+	"errors"
+	klarna "github.com/Flaconi/go-klarna"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type testingCheckout struct {
+	klarna.CheckoutContextSrv
+	orders map[string]*klarna.CheckoutOrder
+	err    error
+	delay  time.Duration
+}
+
+func (c *testingCheckout) RetrieveOrderContext(ctx context.Context, id string) (*klarna.CheckoutOrder, error) {
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if nil != c.err {
+		return nil, c.err
+	}
+	order, ok := c.orders[id]
+	if !ok {
+		return nil, &klarna.APIError{StatusCode: http.StatusNotFound}
+	}
+
+	return order, nil
+}
+
+func signedURLs(t *testing.T, h *Handler) *klarna.CheckoutMerchantURLS {
+	urls, err := h.MerchantURLs("https://shop.example/klarna", Pages{})
+	assert.Nil(t, err)
+
+	return urls
+}
+
+func TestHandler_MerchantURLs(t *testing.T) {
+	assertions := assert.New(t)
+
+	h := NewHandler(&testingMerchant{}, Config{Secret: []byte("secret")})
+	urls := signedURLs(t, h)
+
+	token := tokenOf(urls.Push)
+	assertions.True(h.validToken(token))
+	assertions.Contains(urls.Push, "?klarna_order_id={checkout.order.id}&klarna_token=")
+	for _, u := range []string{
+		urls.Validation, urls.ShippingOptionUpdate, urls.AddressUpdate, urls.Notification, urls.CountryChange,
+	} {
+		assertions.Equal(token, tokenOf(u), u)
+	}
+	assertions.NotEqual(token, tokenOf(signedURLs(t, h).Push))
+
+	assertions.False(NewHandler(&testingMerchant{}, Config{Secret: []byte("other")}).validToken(token))
+
+	urls = signedURLs(t, NewHandler(&testingMerchant{}, Config{}))
+	assertions.Empty(tokenOf(urls.Push))
+}
+
+func TestHandler_VerifyToken(t *testing.T) {
+	assertions := assert.New(t)
+
+	m := &testingMerchant{}
+	h := NewHandler(m, Config{Secret: []byte("secret")})
+	token := tokenOf(signedURLs(t, h).Push)
+
+	rec := callback(h, http.MethodPost, "/klarna/push?klarna_order_id=abc&klarna_token="+token, "")
+	assertions.Equal(http.StatusOK, rec.Code)
+
+	rec = callback(h, http.MethodPost, "/klarna/push?klarna_order_id=abc", "")
+	assertions.Equal(http.StatusForbidden, rec.Code)
+	assertions.Contains(rec.Body.String(), ErrMissingToken.Error())
+
+	rec = callback(h, http.MethodPost, "/klarna/validation?klarna_token=forged.token", `{"order_id":"abc"}`)
+	assertions.Equal(http.StatusForbidden, rec.Code)
+	assertions.Contains(rec.Body.String(), ErrInvalidToken.Error())
+
+	assertions.Equal([]string{"abc"}, m.pushed)
+}
+
+func TestHandler_VerifyOrder(t *testing.T) {
+	assertions := assert.New(t)
+
+	checkout := &testingCheckout{orders: map[string]*klarna.CheckoutOrder{}}
+	m := &testingMerchant{}
+	h := NewHandler(m, Config{Secret: []byte("secret"), Orders: checkout})
+	urls := signedURLs(t, h)
+	token := url.QueryEscape(tokenOf(urls.Push))
+	checkout.orders["abc"] = &klarna.CheckoutOrder{ID: "abc", MerchantURLS: urls}
+	checkout.orders["def"] = &klarna.CheckoutOrder{ID: "def", MerchantURLS: signedURLs(t, h)}
+
+	rec := callback(h, http.MethodPost, "/klarna/push?klarna_order_id=abc&klarna_token="+token, "")
+	assertions.Equal(http.StatusOK, rec.Code)
+
+	rec = callback(h, http.MethodPost, "/klarna/push?klarna_order_id=def&klarna_token="+token, "")
+	assertions.Equal(http.StatusForbidden, rec.Code)
+	assertions.Contains(rec.Body.String(), ErrForeignToken.Error())
+
+	rec = callback(h, http.MethodPost, "/klarna/notification?klarna_token="+token, `{"order_id":"xyz"}`)
+	assertions.Equal(http.StatusForbidden, rec.Code)
+	assertions.Contains(rec.Body.String(), ErrUnknownOrder.Error())
+
+	checkout.err = errors.New("service unavailable")
+	rec = callback(h, http.MethodPost, "/klarna/push?klarna_order_id=abc&klarna_token="+token, "")
+	assertions.Equal(http.StatusInternalServerError, rec.Code)
+
+	assertions.Equal([]string{"abc"}, m.pushed)
+}
+
+func TestHandler_VerifyValidationUsesFetchedOrder(t *testing.T) {
+	assertions := assert.New(t)
+
+	var validated *klarna.CheckoutOrder
+	checkout := &testingCheckout{orders: map[string]*klarna.CheckoutOrder{
+		"abc": {ID: "abc", OrderAmount: 1000},
+	}}
+	h := NewHandler(&validatingMerchant{validated: &validated}, Config{Orders: checkout})
+
+	rec := callback(h, http.MethodPost, "/klarna/validation", `{"order_id":"abc","order_amount":1}`)
+	assertions.Equal(http.StatusOK, rec.Code)
+	assertions.Equal(1000, validated.OrderAmount)
+}
+
+func TestHandler_VerifyValidationDeadline(t *testing.T) {
+	assertions := assert.New(t)
+
+	var validated *klarna.CheckoutOrder
+	checkout := &testingCheckout{orders: map[string]*klarna.CheckoutOrder{"abc": {ID: "abc"}}, delay: time.Second}
+	h := NewHandler(&validatingMerchant{validated: &validated}, Config{
+		Orders:            checkout,
+		ValidationTimeout: 20 * time.Millisecond,
+		DefaultDecision:   RejectWithRedirect("https://shop.example/checkout/retry"),
+	})
+
+	start := time.Now()
+	rec := callback(h, http.MethodPost, "/klarna/validation", `{"order_id":"abc"}`)
+	assertions.Less(time.Since(start), 500*time.Millisecond)
+	assertions.Equal(http.StatusSeeOther, rec.Code)
+	assertions.Equal("https://shop.example/checkout/retry", rec.Header().Get("Location"))
+	assertions.Nil(validated)
+
+	h = NewHandler(&validatingMerchant{validated: &validated}, Config{
+		Orders:          &testingCheckout{err: errors.New("klarna unavailable")},
+		DefaultDecision: RejectWithRedirect("https://shop.example/checkout/retry"),
+	})
+	rec = callback(h, http.MethodPost, "/klarna/validation", `{"order_id":"abc"}`)
+	assertions.Equal(http.StatusSeeOther, rec.Code)
+	assertions.Nil(validated)
+}
+
+func TestVerificationError(t *testing.T) {
+	assertions := assert.New(t)
+
+	var err error = &VerificationError{Callback: "push", OrderID: "abc", Err: ErrInvalidToken}
+	assertions.EqualError(err, `push callback for order "abc" failed verification: callback token is invalid`)
+	assertions.True(errors.Is(err, ErrInvalidToken))
+}
+
+type validatingMerchant struct {
+	BaseMerchant
+	validated **klarna.CheckoutOrder
+}
+
+func (m *validatingMerchant) Validate(_ context.Context, order *klarna.CheckoutOrder) (ValidationDecision, error) {
+	*m.validated = order
+
+	return Accept(), nil
+}
@@ -1,7 +1,8 @@
 package go_klarna
 
 import (
-	"encoding/json"
+	"context"
+	"net/http"
 )
 
 const (
@@ -22,6 +23,15 @@ type (
 		UpdateOrder(string, *CheckoutOrder) error
 	}
 
+	// CheckoutContextSrv type is the CheckoutSrv with context aware variants of its methods
+	CheckoutContextSrv interface {
+		CheckoutSrv
+
+		CreateNewOrderContext(context.Context, *CheckoutOrder) error
+		RetrieveOrderContext(context.Context, string) (*CheckoutOrder, error)
+		UpdateOrderContext(context.Context, string, *CheckoutOrder) error
+	}
+
 	checkoutSrv struct {
 		client Client
 	}
@@ -194,40 +204,58 @@ type (
 
 // CreateNewOrder method create a new order on the Klarna API
 func (srv *checkoutSrv) CreateNewOrder(o *CheckoutOrder) error {
-	res, err := srv.client.Post(checkoutEndPoint, o)
-	if nil != err {
-		return err
-	}
+	return srv.CreateNewOrderContext(context.Background(), o)
+}
+
+// CreateNewOrderContext method is the context aware version of CreateNewOrder
+func (srv *checkoutSrv) CreateNewOrderContext(ctx context.Context, o *CheckoutOrder) error {
+	_, err := execute(ctx, srv.client, request{
+		op:     "Checkout.CreateNewOrder",
+		method: http.MethodPost,
+		route:  checkoutEndPoint,
+		body:   o,
+	}, o)
 
-	return json.NewDecoder(res.Body).Decode(o)
+	return err
 }
 
 // RetrieveOrder method fetches an order by its ID
 func (srv *checkoutSrv) RetrieveOrder(id string) (*CheckoutOrder, error) {
-	path := checkoutEndPoint + "/" + id
-	res, err := srv.client.Get(path)
-	if nil != err {
-		return nil, err
-	}
-	o := new(CheckoutOrder)
-	err = json.NewDecoder(res.Body).Decode(o)
+	return srv.RetrieveOrderContext(context.Background(), id)
+}
+
+// RetrieveOrderContext method is the context aware version of RetrieveOrder
+func (srv *checkoutSrv) RetrieveOrderContext(ctx context.Context, id string) (*CheckoutOrder, error) {
+	o, _, err := fetch[CheckoutOrder](ctx, srv.client, request{
+		op:     "Checkout.RetrieveOrder",
+		method: http.MethodGet,
+		route:  checkoutEndPoint + "/{order_id}",
+		params: []string{id},
+	})
 
 	return o, err
 }
 
 // UpdateOrder method updates an order by a given ID and CheckOrder structure, returns error if there is any
 func (srv *checkoutSrv) UpdateOrder(id string, o *CheckoutOrder) error {
-	path := checkoutEndPoint + "/" + id
-	res, err := srv.client.Post(path, o)
-	if nil != err {
-		return err
-	}
+	return srv.UpdateOrderContext(context.Background(), id, o)
+}
+
+// UpdateOrderContext method is the context aware version of UpdateOrder
+func (srv *checkoutSrv) UpdateOrderContext(ctx context.Context, id string, o *CheckoutOrder) error {
+	_, err := execute(ctx, srv.client, request{
+		op:     "Checkout.UpdateOrder",
+		method: http.MethodPost,
+		route:  checkoutEndPoint + "/{order_id}",
+		params: []string{id},
+		body:   o,
+	}, o)
 
-	return json.NewDecoder(res.Body).Decode(o)
+	return err
 }
 
 // NewCheckoutSrv factory method for the checkoutSrv
-func NewCheckoutSrv(c Client) CheckoutSrv {
+func NewCheckoutSrv(c Client) CheckoutContextSrv {
 	return &checkoutSrv{
 		c,
 	}
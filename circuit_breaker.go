@@ -0,0 +1,244 @@
+package go_klarna
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// Circuit breaker states
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+
+	defaultFailureRate    = 0.5
+	defaultMinRequests    = 10
+	defaultBreakerWindow  = 30 * time.Second
+	defaultOpenTimeout    = 30 * time.Second
+	defaultHalfOpenProbes = 1
+)
+
+// ErrCircuitOpen error describes a request that was not sent because Klarna API is considered unavailable
+var ErrCircuitOpen = errors.New("circuit breaker is open, Klarna API is considered unavailable")
+
+type (
+	// CircuitState type is the state of the circuit breaker of one endpoint family
+	CircuitState int
+
+	// CircuitBreakerConfig type configures the circuit breakers of the client, there is one per endpoint family.
+	// 5xx answers, timeouts and transport errors count as failures
+	CircuitBreakerConfig struct {
+		// FailureRate opens the circuit once reached, between 0 and 1, defaults to 0.5
+		FailureRate float64
+		// MinRequests is the amount of requests within the window before the failure rate is considered,
+		// defaults to 10
+		MinRequests int
+		// Window is the period the failures are counted over, defaults to 30s
+		Window time.Duration
+		// OpenTimeout is how long the circuit stays open before probing Klarna again, defaults to 30s
+		OpenTimeout time.Duration
+		// HalfOpenProbes is the amount of successful probes closing the circuit again, defaults to 1
+		HalfOpenProbes int
+		// OnStateChange is called on every state change, e.g. to hide Klarna as payment method while it is down
+		OnStateChange func(family EndpointFamily, from, to CircuitState)
+	}
+
+	// breaker type is the circuit breaker of one endpoint family, a nil breaker lets every request through
+	breaker struct {
+		family EndpointFamily
+		config CircuitBreakerConfig
+
+		mu    sync.Mutex
+		state CircuitState
+		// generation is incremented on every state change, the outcome of a request allowed in an earlier
+		// generation is ignored
+		generation  uint64
+		windowStart time.Time
+		requests    int
+		failures    int
+		openedAt    time.Time
+		probes      int
+		successes   int
+	}
+)
+
+// String method returns the name of the state
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+
+	return "unknown"
+}
+
+// newBreakers function creates a circuit breaker for every endpoint family, nil config disables them
+func newBreakers(config *CircuitBreakerConfig) map[EndpointFamily]*breaker {
+	if nil == config {
+		return nil
+	}
+
+	c := *config
+	if 0 >= c.FailureRate {
+		c.FailureRate = defaultFailureRate
+	}
+	if 0 >= c.MinRequests {
+		c.MinRequests = defaultMinRequests
+	}
+	if 0 >= c.Window {
+		c.Window = defaultBreakerWindow
+	}
+	if 0 >= c.OpenTimeout {
+		c.OpenTimeout = defaultOpenTimeout
+	}
+	if 0 >= c.HalfOpenProbes {
+		c.HalfOpenProbes = defaultHalfOpenProbes
+	}
+
+	breakers := make(map[EndpointFamily]*breaker)
+	for _, family := range []EndpointFamily{CheckoutFamily, PaymentsFamily, OrderManagementFamily, OtherFamily} {
+		breakers[family] = &breaker{family: family, config: c}
+	}
+
+	return breakers
+}
+
+// allow method tells whether a request may be sent, it fails with ErrCircuitOpen while the circuit is open or all
+// the probes of a half open circuit are in progress. The returned generation has to be handed to record along with
+// the outcome of the request
+func (b *breaker) allow(now time.Time) (uint64, error) {
+	if nil == b {
+		return 0, nil
+	}
+
+	b.mu.Lock()
+	from := b.state
+	switch b.state {
+	case CircuitOpen:
+		if now.Sub(b.openedAt) < b.config.OpenTimeout {
+			b.mu.Unlock()
+			return 0, ErrCircuitOpen
+		}
+		b.enter(CircuitHalfOpen)
+		b.probes = 1
+		b.successes = 0
+	case CircuitHalfOpen:
+		if b.probes >= b.config.HalfOpenProbes {
+			b.mu.Unlock()
+			return 0, ErrCircuitOpen
+		}
+		b.probes++
+	}
+	to, generation := b.state, b.generation
+	b.mu.Unlock()
+
+	b.notify(from, to)
+
+	return generation, nil
+}
+
+// record method accounts the outcome of a request that was allowed through in the given generation, the outcomes of
+// the requests allowed before the last state change are ignored: in half open state only the probes count
+func (b *breaker) record(ctx context.Context, now time.Time, generation uint64, err error) {
+	if nil == b {
+		return
+	}
+
+	failed := isBreakerFailure(ctx, err)
+	cancelled := isCancelled(ctx, err)
+
+	b.mu.Lock()
+	if generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+	from := b.state
+	switch b.state {
+	case CircuitHalfOpen:
+		b.probes--
+		if cancelled {
+			// the probe tells nothing about Klarna, let another one take its place
+			break
+		}
+		if failed {
+			b.trip(now)
+			break
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenProbes {
+			b.enter(CircuitClosed)
+			b.resetWindow(now)
+		}
+	case CircuitClosed:
+		if now.Sub(b.windowStart) > b.config.Window {
+			b.resetWindow(now)
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.config.MinRequests &&
+			float64(b.failures)/float64(b.requests) >= b.config.FailureRate {
+			b.trip(now)
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// enter method changes the state and starts a new generation, the lock must be held
+func (b *breaker) enter(state CircuitState) {
+	b.state = state
+	b.generation++
+}
+
+// trip method opens the circuit, the lock must be held
+func (b *breaker) trip(now time.Time) {
+	b.enter(CircuitOpen)
+	b.openedAt = now
+	b.probes = 0
+	b.successes = 0
+}
+
+// resetWindow method starts a new counting window, the lock must be held
+func (b *breaker) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.requests = 0
+	b.failures = 0
+}
+
+// notify method publishes a state change, it must be called without holding the lock
+func (b *breaker) notify(from, to CircuitState) {
+	if from != to && nil != b.config.OnStateChange {
+		b.config.OnStateChange(b.family, from, to)
+	}
+}
+
+// isBreakerFailure function tells whether the outcome of a request hints at Klarna being unavailable, requests
+// cancelled by the caller are not held against Klarna
+func isBreakerFailure(ctx context.Context, err error) bool {
+	if nil == err || isCancelled(ctx, err) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return http.StatusInternalServerError <= apiErr.StatusCode
+	}
+
+	return true
+}
+
+// isCancelled function tells whether a request failed because the caller cancelled it
+func isCancelled(ctx context.Context, err error) bool {
+	return nil != err && errors.Is(ctx.Err(), context.Canceled)
+}
@@ -0,0 +1,154 @@
+package go_klarna
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func allowed(assertions *assert.Assertions, b *breaker, now time.Time) uint64 {
+	generation, err := b.allow(now)
+	assertions.Nil(err)
+
+	return generation
+}
+
+func TestBreaker_StateMachine(t *testing.T) {
+	assertions := assert.New(t)
+
+	var changes []CircuitState
+	b := newBreakers(&CircuitBreakerConfig{
+		FailureRate:    0.5,
+		MinRequests:    4,
+		OpenTimeout:    time.Second,
+		HalfOpenProbes: 2,
+		OnStateChange: func(family EndpointFamily, from, to CircuitState) {
+			assertions.Equal(CheckoutFamily, family)
+			changes = append(changes, to)
+		},
+	})[CheckoutFamily]
+	ctx := context.Background()
+	now := time.Now()
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable}
+
+	for _, err := range []error{nil, unavailable, &APIError{StatusCode: http.StatusNotFound}, unavailable} {
+		b.record(ctx, now, allowed(assertions, b, now), err)
+	}
+	assertions.Equal(CircuitOpen, b.state)
+	_, err := b.allow(now.Add(500 * time.Millisecond))
+	assertions.ErrorIs(err, ErrCircuitOpen)
+
+	probeTime := now.Add(time.Second)
+	first := allowed(assertions, b, probeTime)
+	second := allowed(assertions, b, probeTime)
+	_, err = b.allow(probeTime)
+	assertions.ErrorIs(err, ErrCircuitOpen)
+	b.record(ctx, probeTime, first, nil)
+	b.record(ctx, probeTime, second, unavailable)
+	assertions.Equal(CircuitOpen, b.state)
+
+	probeTime = probeTime.Add(time.Second)
+	first = allowed(assertions, b, probeTime)
+	second = allowed(assertions, b, probeTime)
+	b.record(ctx, probeTime, first, nil)
+	b.record(ctx, probeTime, second, nil)
+	assertions.Equal(CircuitClosed, b.state)
+
+	assertions.Equal([]CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed}, changes)
+}
+
+func TestBreaker_IgnoresCancelledRequests(t *testing.T) {
+	assertions := assert.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assertions.False(isBreakerFailure(ctx, context.Canceled))
+	assertions.True(isBreakerFailure(context.Background(), context.DeadlineExceeded))
+	assertions.False(isBreakerFailure(context.Background(), &APIError{StatusCode: http.StatusTooManyRequests}))
+	assertions.True(isBreakerFailure(context.Background(), &APIError{StatusCode: http.StatusBadGateway}))
+}
+
+func TestBreaker_CancelledProbeIsInconclusive(t *testing.T) {
+	assertions := assert.New(t)
+
+	b := newBreakers(&CircuitBreakerConfig{MinRequests: 1, OpenTimeout: time.Second})[CheckoutFamily]
+	now := time.Now()
+	b.record(context.Background(), now, allowed(assertions, b, now), &APIError{StatusCode: http.StatusServiceUnavailable})
+	assertions.Equal(CircuitOpen, b.state)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	probeTime := now.Add(time.Second)
+	b.record(ctx, probeTime, allowed(assertions, b, probeTime), context.Canceled)
+	assertions.Equal(CircuitHalfOpen, b.state)
+	assertions.Equal(0, b.successes)
+
+	b.record(context.Background(), probeTime, allowed(assertions, b, probeTime), nil)
+	assertions.Equal(CircuitClosed, b.state)
+}
+
+func TestBreaker_IgnoresStaleOutcomes(t *testing.T) {
+	assertions := assert.New(t)
+
+	b := newBreakers(&CircuitBreakerConfig{MinRequests: 1, OpenTimeout: time.Second})[CheckoutFamily]
+	ctx := context.Background()
+	now := time.Now()
+	slow := allowed(assertions, b, now)
+	b.record(ctx, now, allowed(assertions, b, now), &APIError{StatusCode: http.StatusServiceUnavailable})
+	assertions.Equal(CircuitOpen, b.state)
+
+	probeTime := now.Add(time.Second)
+	probe := allowed(assertions, b, probeTime)
+	b.record(ctx, probeTime, slow, nil)
+	assertions.Equal(CircuitHalfOpen, b.state)
+	assertions.Equal(1, b.probes)
+	assertions.Equal(0, b.successes)
+	_, err := b.allow(probeTime)
+	assertions.ErrorIs(err, ErrCircuitOpen)
+
+	b.record(ctx, probeTime, probe, &APIError{StatusCode: http.StatusServiceUnavailable})
+	assertions.Equal(CircuitOpen, b.state)
+	b.record(ctx, probeTime, probe, nil)
+	assertions.Equal(CircuitOpen, b.state)
+}
+
+func TestClient_CircuitBreakerFailsFast(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	calls := 0
+	testingMux.HandleFunc("/checkout/v3/orders/abc", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	var opened EndpointFamily
+	uri, _ := url.Parse(testingServer.URL)
+	c := NewClient(Config{
+		BaseURL: uri,
+		CircuitBreaker: &CircuitBreakerConfig{
+			MinRequests: 2,
+			OnStateChange: func(family EndpointFamily, from, to CircuitState) {
+				opened = family
+			},
+		},
+	})
+	srv := NewCheckoutSrv(c)
+
+	for i := 0; i < 2; i++ {
+		_, err := srv.RetrieveOrder("abc")
+		assertions.ErrorIs(err, ServiceUnavailable)
+	}
+	_, err := srv.RetrieveOrder("abc")
+
+	assertions.ErrorIs(err, ErrCircuitOpen)
+	assertions.Equal(2, calls)
+	assertions.Equal(CheckoutFamily, opened)
+
+	_, err = NewOrderManagement(c).GetOrder("abc")
+	assertions.NotErrorIs(err, ErrCircuitOpen)
+}
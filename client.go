@@ -2,10 +2,14 @@ package go_klarna
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
@@ -32,10 +36,63 @@ var (
 
 // Config type is the basic configurations required from the client to provide in order to function
 type Config struct {
+	// BaseURL of the Klarna API, when nil it is resolved from Environment and Region, or else from the API username
 	BaseURL     *url.URL
 	APIUsername string
 	APIPassword string
-	Timeout     time.Duration
+	// Credentials is asked for the API username and password before every request, it takes precedence over
+	// APIUsername and APIPassword
+	Credentials CredentialsProvider
+	// Timeout is the deadline budget of an operation, it is shared by all its attempts when retries are enabled and
+	// lasts until the response body is closed. Defaults to 5 seconds, a negative value disables it
+	Timeout time.Duration
+	// OperationTimeouts overrides Timeout for single operations, keyed by the operation name, e.g.
+	// "Checkout.RetrieveOrder"
+	OperationTimeouts map[string]time.Duration
+	// FamilyTimeouts overrides Timeout for the operations of an endpoint family without a timeout of their own
+	FamilyTimeouts map[EndpointFamily]time.Duration
+	// Environment selects between the production and playground APIs, defaults to Production
+	Environment Environment
+	// Region selects the regional API, defaults to the region of the API username
+	Region Region
+	// RejectRegionMismatch makes the client refuse to send any request when the API username does not belong to the
+	// environment and region of the base URL, it is checked against the credentials of every request
+	RejectRegionMismatch bool
+	// Retry enables retrying failed requests, nil means every request is attempted only once
+	Retry *RetryPolicy
+	// HTTPClient is used to execute the requests when set, its own timeout applies on top of the deadline budget
+	HTTPClient *http.Client
+	// Middleware wraps every request sent to Klarna, the first middleware is the outermost one
+	Middleware []Middleware
+	// TracerProvider creates a span for every operation, defaults to the global OpenTelemetry provider
+	TracerProvider trace.TracerProvider
+	// Metrics receives the measurements of every operation
+	Metrics MetricsRecorder
+	// Logger logs every request and response with the personal data of the customer redacted, nil disables logging
+	Logger *slog.Logger
+	// LogLevel is the level requests and responses are logged at, defaults to slog.LevelInfo
+	LogLevel slog.Level
+	// RateLimits caps the requests sent to each endpoint family, families without an entry are not limited. A 429
+	// answer always holds back the whole family for the time given by Klarna's Retry-After header
+	RateLimits map[EndpointFamily]RateLimit
+	// CircuitBreaker fails requests fast with ErrCircuitOpen while Klarna is unavailable, nil disables it
+	CircuitBreaker *CircuitBreakerConfig
+	// DryRun answers the POST, PATCH and DELETE calls of the order management and payments services with synthetic
+	// success responses instead of sending them, the calls are recorded in it. GET requests go through as usual
+	DryRun *DryRun
+	// WireDump receives every exchange with Klarna, headers, bodies and timing, for debugging. Credentials are masked
+	// and the personal data of the customer is redacted, nil disables it. See NewRotatingFile
+	WireDump io.Writer
+	// AppName and AppVersion identify your application in the User-Agent header sent to Klarna
+	AppName    string
+	AppVersion string
+	// Platform and PlatformVersion identify the e-commerce platform the integration runs on, e.g. "Shopware", in the
+	// User-Agent header
+	Platform        string
+	PlatformVersion string
+	// Headers are sent with every request, e.g. the partner or platform identification headers Klarna assigned to
+	// your integration. They can not replace the headers set by the client itself
+	Headers http.Header
 }
 
 // Client type abstract the functionality that the client should implement, just for more extendability
@@ -46,56 +103,205 @@ type Client interface {
 	Delete(path string) (*http.Response, error)
 }
 
+// ContextClient type is the Client with context aware variants of its methods, the request is cancelled as soon as
+// the given context is done. The services fall back to the plain methods for a Client that does not implement it
+type ContextClient interface {
+	Client
+
+	PostContext(ctx context.Context, path string, body interface{}) (*http.Response, error)
+	PatchContext(ctx context.Context, path string, body interface{}) (*http.Response, error)
+	GetContext(ctx context.Context, path string) (*http.Response, error)
+	DeleteContext(ctx context.Context, path string) (*http.Response, error)
+}
+
 type client struct {
-	config Config
-	client *http.Client
+	config      Config
+	client      *http.Client
+	credentials CredentialsProvider
+	handler     Handler
+	tracer      trace.Tracer
+	metrics     MetricsRecorder
+	userAgent   string
+	// limiters is keyed by every endpoint family and never modified after the client is created
+	limiters map[EndpointFamily]*limiter
+	// breakers is nil when the circuit breaker is disabled
+	breakers map[EndpointFamily]*breaker
+	// wire is nil when the wire dump is disabled
+	wire *wireDump
+	// configErr is returned for every request when the configuration is unusable
+	configErr error
 }
 
 // Post method executes a Post request on the given path with the given body, if the body is empty will be omitted
 func (c *client) Post(path string, body interface{}) (*http.Response, error) {
-	return c.do(http.MethodPost, path, body)
+	return c.PostContext(context.Background(), path, body)
 }
 
 // Get method fetches the content of the given path, return http response and error interface if there is any
 func (c *client) Get(path string) (*http.Response, error) {
-	return c.do(http.MethodGet, path, nil)
+	return c.GetContext(context.Background(), path)
 }
 
 // Delete method executes a Delete request on the given path and returns response pointer and error interface if there
 // is any
 func (c *client) Delete(path string) (*http.Response, error) {
-	return c.do(http.MethodDelete, path, nil)
+	return c.DeleteContext(context.Background(), path)
 }
 
 // Post method executes a Post request on the given path with the given body, if the body is empty will be omitted
 func (c *client) Patch(path string, body interface{}) (*http.Response, error) {
-	return c.do(http.MethodPatch, path, body)
+	return c.PatchContext(context.Background(), path, body)
+}
+
+// PostContext method is the context aware version of Post
+func (c *client) PostContext(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	return c.do(ctx, http.MethodPost, path, body)
+}
+
+// GetContext method is the context aware version of Get
+func (c *client) GetContext(ctx context.Context, path string) (*http.Response, error) {
+	return c.do(ctx, http.MethodGet, path, nil)
+}
+
+// DeleteContext method is the context aware version of Delete
+func (c *client) DeleteContext(ctx context.Context, path string) (*http.Response, error) {
+	return c.do(ctx, http.MethodDelete, path, nil)
 }
 
-func (c *client) do(method string, path string, body interface{}) (*http.Response, error) {
+// PatchContext method is the context aware version of Patch
+func (c *client) PatchContext(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	return c.do(ctx, http.MethodPatch, path, body)
+}
+
+func (c *client) do(ctx context.Context, method string, path string, body interface{}) (*http.Response, error) {
+	if nil != c.configErr {
+		return nil, c.configErr
+	}
+
+	op := operationFor(ctx, method, path)
+	ctx, cancel := c.withBudget(ctx, op)
+	ctx, span := c.startSpan(ctx, op, method)
+	c.metrics.InFlight(op, 1)
+	start := time.Now()
+
+	res, attempts, err := c.retry(ctx, op, method, path, body)
+	if nil == res {
+		cancel()
+	} else {
+		res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	}
+
+	o := outcomeOf(res, err)
+	c.metrics.Observe(op, o.status, o.errorCode, time.Since(start))
+	c.metrics.InFlight(op, -1)
+	endSpan(span, res, attempts, err)
+
+	return res, err
+}
+
+// retry method executes the request until it succeeds or the retry policy gives up, it returns the number of attempts
+// made along with the outcome of the last one
+func (c *client) retry(
+	ctx context.Context,
+	op Operation,
+	method, path string,
+	body interface{},
+) (*http.Response, int, error) {
 	uri := fmt.Sprintf(
 		"%s://%s%s",
 		c.config.BaseURL.Scheme,
 		strings.TrimRight(c.config.BaseURL.Host, "/"),
 		path,
 	)
-	var reader io.Reader
+	var payload []byte
 	if nil != body {
 		bytesBody, err := json.Marshal(body)
 		if nil != err {
-			return nil, err
+			return nil, 0, err
 		}
-		reader = bytes.NewReader(bytesBody)
+		payload = bytesBody
 	}
 
-	req, err := http.NewRequest(method, uri, reader)
+	idempotencyKey := idempotencyKeyFor(ctx, method, path)
+	lim := c.limiters[op.Family()]
+	br := c.breakers[op.Family()]
+	for attempt := 1; ; attempt++ {
+		creds, err := c.resolveCredentials(ctx)
+		if nil != err {
+			return nil, attempt - 1, err
+		}
+		release, err := lim.acquire(ctx)
+		if nil != err {
+			return nil, attempt - 1, err
+		}
+		generation, err := br.allow(time.Now())
+		if nil != err {
+			release()
+			return nil, attempt - 1, err
+		}
+		res, err := c.send(ctx, op, method, uri, payload, idempotencyKey, creds)
+		release()
+		br.record(ctx, time.Now(), generation, err)
+		if nil == err {
+			return res, attempt, nil
+		}
+
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && http.StatusTooManyRequests == apiErr.StatusCode {
+			lim.throttle(apiErr.RetryAfter)
+		}
+		if errors.As(err, &apiErr) && http.StatusUnauthorized == apiErr.StatusCode {
+			// the keys may have been rotated, make the provider hand out fresh ones for the next request
+			if inv, ok := c.credentials.(invalidator); ok {
+				inv.Invalidate()
+			}
+		}
+		if !c.config.Retry.shouldRetry(ctx, attempt, method, idempotencyKey, err) {
+			return nil, attempt, err
+		}
+		wait := c.config.Retry.backoff(attempt, err)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			// the deadline budget would be spent before the next attempt even starts
+			return nil, attempt, err
+		}
+		c.metrics.Retried(op)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, attempt, err
+		case <-timer.C:
+		}
+	}
+}
+
+// send method executes a single attempt of a request against the Klarna API
+func (c *client) send(
+	ctx context.Context,
+	op Operation,
+	method, uri string,
+	payload []byte,
+	idempotencyKey string,
+	creds Credentials,
+) (*http.Response, error) {
+	var reader io.Reader
+	if nil != payload {
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, uri, reader)
 	if nil != err {
 		return nil, err
 	}
 
-	req.Header.Add("Content-Type", "application/json")
-	req.SetBasicAuth(c.config.APIUsername, c.config.APIPassword)
-	res, err := c.client.Do(req)
+	c.setIdentification(req)
+	req.Header.Set("Content-Type", "application/json")
+	if "" != idempotencyKey {
+		req.Header.Set(idempotencyKeyHeader, idempotencyKey)
+	}
+	req.SetBasicAuth(creds.Username, creds.Password)
+	res, err := c.roundTrip(op, req)
 	if nil != err {
 		return nil, err
 	}
@@ -108,40 +314,97 @@ func (c *client) do(method string, path string, body interface{}) (*http.Respons
 	return res, nil
 }
 
-// errorFromResponse method translates the sent status code into an internal error
+// resolveCredentials method asks the provider for the credentials of the next attempt and makes sure they belong to
+// the region of the base URL when RejectRegionMismatch is set
+func (c *client) resolveCredentials(ctx context.Context) (Credentials, error) {
+	creds, err := c.credentials.Credentials(ctx)
+	if nil != err {
+		return creds, err
+	}
+	if c.config.RejectRegionMismatch {
+		if err = checkRegion(creds.Username, c.config.BaseURL); nil != err {
+			return creds, err
+		}
+	}
+
+	return creds, nil
+}
+
+// roundTrip method passes the request through the configured middlewares down to the HTTP client
+func (c *client) roundTrip(op Operation, req *http.Request) (*http.Response, error) {
+	return c.handler(op, req)
+}
+
+// transport method is the innermost Handler, it hands the request over to the HTTP client
+func (c *client) transport(_ Operation, req *http.Request) (*http.Response, error) {
+	return c.client.Do(req)
+}
+
+// errorFromResponse method translates a non successful response into an *APIError, the body of such a response is
+// consumed and closed. The returned error can be matched against the package sentinel errors with errors.Is
 func (c *client) errorFromResponse(res *http.Response) error {
 	if res.StatusCode > 299 {
-		switch res.StatusCode {
-		case http.StatusBadRequest:
-			return ErrOrderCreate
-		case http.StatusUnauthorized:
-			return ErrUnAuthorized
-		case http.StatusForbidden:
-			return ErrReadOnlyResource
-		case http.StatusNotFound:
-			return ErrOrderNotFound
-		default:
-			return ServiceUnavailable
-		}
+		defer res.Body.Close()
+		return newAPIError(res)
 	}
 
 	return nil
 }
 
 // NewClient factory method
-func NewClient(c Config) Client {
+func NewClient(c Config) ContextClient {
+	var configErr error
 	if nil == c.BaseURL {
-		uri, _ := url.Parse(EuroAPI)
+		uri, err := c.baseURL()
+		if nil != err {
+			// every request fails with configErr, the placeholder is never called
+			configErr = err
+			uri, _ = url.Parse(EuroAPI)
+		}
 		c.BaseURL = uri
 	}
 	if 0 == c.Timeout {
-		c.Timeout = time.Second * 5
+		c.Timeout = defaultTimeout
+	}
+
+	httpClient := c.HTTPClient
+	if nil == httpClient {
+		httpClient = &http.Client{}
 	}
 
-	return &client{
-		config: c,
-		client: &http.Client{
-			Timeout: c.Timeout,
-		},
+	tp := c.TracerProvider
+	if nil == tp {
+		tp = otel.GetTracerProvider()
 	}
+
+	var metrics MetricsRecorder = nopMetrics{}
+	if nil != c.Metrics {
+		metrics = c.Metrics
+	}
+
+	cl := &client{
+		config:      c,
+		client:      httpClient,
+		credentials: c.credentialsProvider(),
+		tracer:      tp.Tracer(tracerName),
+		metrics:     metrics,
+		limiters:    newLimiters(c.RateLimits),
+		breakers:    newBreakers(c.CircuitBreaker),
+		configErr:   configErr,
+		userAgent:   userAgent(c),
+	}
+	middlewares := append([]Middleware{}, c.Middleware...)
+	if nil != c.Logger {
+		middlewares = append(middlewares, cl.logExchanges)
+	}
+	if nil != c.WireDump {
+		cl.wire = &wireDump{w: c.WireDump}
+		middlewares = append(middlewares, cl.dumpWire)
+	}
+	if nil != c.DryRun {
+		middlewares = append(middlewares, cl.dryRun)
+	}
+	cl.handler = chain(cl.transport, middlewares)
+
+	return cl
 }
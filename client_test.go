@@ -1,6 +1,7 @@
 package go_klarna
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"net/http"
@@ -18,6 +19,23 @@ func TestClient_Get(t *testing.T) {
 
 }
 
+func TestClient_GetContext(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	setupMux(assertions, "/ping", nil, http.MethodGet, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := testingClient()
+	res, err := c.GetContext(ctx, "/ping")
+
+	assertions.Nil(res)
+	assertions.ErrorIs(err, context.Canceled)
+}
+
 func setupServer() {
 	testingMux = http.NewServeMux()
 	testingServer = httptest.NewServer(testingMux)
@@ -51,12 +69,9 @@ func tearDown() {
 
 func testingClient() *client {
 	uri, _ := url.Parse(testingServer.URL)
-	return &client{
-		Config{
-			BaseURL:     uri,
-			APIPassword: "somePass",
-			APIUsername: "someUser",
-		},
-		http.DefaultClient,
-	}
+	return NewClient(Config{
+		BaseURL:     uri,
+		APIPassword: "somePass",
+		APIUsername: "someUser",
+	}).(*client)
 }
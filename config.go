@@ -0,0 +1,198 @@
+package go_klarna
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// RegionEnv is the environment variable LoadConfig reads the region from, e.g. "eu"
+	RegionEnv = "KLARNA_REGION"
+	// EnvironmentEnv is the environment variable LoadConfig reads the environment from, "production" or "playground"
+	EnvironmentEnv = "KLARNA_ENV"
+	// BaseURLEnv is the environment variable LoadConfig reads the base URL from, it takes precedence over the region
+	BaseURLEnv = "KLARNA_BASE_URL"
+	// TimeoutEnv is the environment variable LoadConfig reads the timeout from, e.g. "5s"
+	TimeoutEnv = "KLARNA_TIMEOUT"
+
+	// minTimeout and maxTimeout bound the timeouts considered sane by Validate
+	minTimeout = 100 * time.Millisecond
+	maxTimeout = 10 * time.Minute
+)
+
+// ErrMissingRegion error is reported by Validate, and returned by every request of the client, for a configuration
+// without a base URL, a region or an API username telling the region
+var ErrMissingRegion = errors.New("neither a base URL nor a region is configured")
+
+// fileConfig type is the layout of the YAML or JSON files read by LoadConfig
+type fileConfig struct {
+	BaseURL              string `yaml:"base_url" json:"base_url"`
+	Username             string `yaml:"username" json:"username"`
+	Password             string `yaml:"password" json:"password"`
+	Environment          string `yaml:"environment" json:"environment"`
+	Region               string `yaml:"region" json:"region"`
+	Timeout              string `yaml:"timeout" json:"timeout"`
+	RejectRegionMismatch bool   `yaml:"reject_region_mismatch" json:"reject_region_mismatch"`
+}
+
+// LoadConfig function builds a Config from the given YAML or JSON file, a path ending in .json is read as JSON and
+// an empty path reads no file at all. The KLARNA_USERNAME, KLARNA_PASSWORD, KLARNA_REGION, KLARNA_ENV,
+// KLARNA_BASE_URL and KLARNA_TIMEOUT environment variables take precedence over the values of the file. The returned
+// error joins every problem found, the config is validated as with Validate
+func LoadConfig(path string) (Config, error) {
+	var fc fileConfig
+	if "" != path {
+		if err := readConfigFile(path, &fc); nil != err {
+			return Config{}, err
+		}
+	}
+
+	overrideFromEnv(&fc.Username, UsernameEnv)
+	overrideFromEnv(&fc.Password, PasswordEnv)
+	overrideFromEnv(&fc.Region, RegionEnv)
+	overrideFromEnv(&fc.Environment, EnvironmentEnv)
+	overrideFromEnv(&fc.BaseURL, BaseURLEnv)
+	overrideFromEnv(&fc.Timeout, TimeoutEnv)
+
+	c := Config{
+		APIUsername:          fc.Username,
+		APIPassword:          fc.Password,
+		Environment:          Environment(strings.ToLower(fc.Environment)),
+		Region:               Region(strings.ToLower(fc.Region)),
+		RejectRegionMismatch: fc.RejectRegionMismatch,
+	}
+
+	var errs []error
+	if "" != fc.BaseURL {
+		uri, err := url.Parse(fc.BaseURL)
+		if nil != err {
+			errs = append(errs, fmt.Errorf("invalid base URL: %w", err))
+		} else {
+			c.BaseURL = uri
+		}
+	}
+	if "" != fc.Timeout {
+		d, err := time.ParseDuration(fc.Timeout)
+		if nil != err {
+			errs = append(errs, fmt.Errorf("invalid timeout: %w", err))
+		} else {
+			c.Timeout = d
+		}
+	}
+	if err := c.Validate(); nil != err {
+		errs = append(errs, err)
+	}
+
+	return c, errors.Join(errs...)
+}
+
+// readConfigFile function decodes the YAML or JSON file at the given path
+func readConfigFile(path string, fc *fileConfig) error {
+	content, err := os.ReadFile(path)
+	if nil != err {
+		return fmt.Errorf("klarna config file: %w", err)
+	}
+
+	if strings.EqualFold(".json", filepath.Ext(path)) {
+		err = json.Unmarshal(content, fc)
+	} else {
+		err = yaml.Unmarshal(content, fc)
+	}
+	if nil != err {
+		return fmt.Errorf("klarna config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// overrideFromEnv function replaces the value with the one of the environment variable when it is set
+func overrideFromEnv(value *string, name string) {
+	if v, ok := os.LookupEnv(name); ok && "" != v {
+		*value = v
+	}
+}
+
+// Validate method reports every configuration error found, joined into one error: missing credentials, an invalid
+// base URL, a missing or unknown region, timeouts out of reason and API credentials of another region than the one of
+// the base URL. The credentials provider is asked for the credentials to check
+func (c Config) Validate() error {
+	var errs []error
+
+	creds, err := c.credentialsProvider().Credentials(context.Background())
+	if nil == err {
+		_, err = creds.check()
+	}
+	if nil != err {
+		errs = append(errs, err)
+	}
+
+	baseURL, err := c.baseURL()
+	if nil != err {
+		errs = append(errs, err)
+	} else if "http" != baseURL.Scheme && "https" != baseURL.Scheme || "" == baseURL.Host {
+		errs = append(errs, fmt.Errorf("base URL %q must be an absolute http or https URL", baseURL))
+		baseURL = nil
+	}
+
+	errs = append(errs, checkTimeout("timeout", c.Timeout))
+	for name, d := range c.OperationTimeouts {
+		errs = append(errs, checkTimeout("timeout of "+name, d))
+	}
+	for family, d := range c.FamilyTimeouts {
+		errs = append(errs, checkTimeout("timeout of the "+string(family)+" family", d))
+	}
+
+	if nil != baseURL {
+		errs = append(errs, checkRegion(creds.Username, baseURL))
+	}
+
+	return errors.Join(errs...)
+}
+
+// baseURL method resolves the base URL of the configuration, without a BaseURL it is built from Environment and
+// Region. Without a Region as well, the environment and region are taken from the API username handed out by the
+// credentials provider
+func (c Config) baseURL() (*url.URL, error) {
+	if nil != c.BaseURL {
+		return c.BaseURL, nil
+	}
+
+	env, region := c.Environment, c.Region
+	if "" == region {
+		creds, err := c.credentialsProvider().Credentials(context.Background())
+		if nil != err {
+			return nil, ErrMissingRegion
+		}
+		credEnv, credRegion, ok := CredentialsRegion(creds.Username)
+		if !ok {
+			return nil, ErrMissingRegion
+		}
+		region = credRegion
+		if "" == env {
+			env = credEnv
+		}
+	}
+
+	return BaseURLFor(env, region)
+}
+
+// checkTimeout function reports a timeout too short to complete any request or too long to be of any use, zero
+// stands for the default and a negative timeout disables it
+func checkTimeout(name string, d time.Duration) error {
+	if 0 < d && d < minTimeout {
+		return fmt.Errorf("%s %s is shorter than %s", name, d, minTimeout)
+	}
+	if d > maxTimeout {
+		return fmt.Errorf("%s %s is longer than %s", name, d, maxTimeout)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package go_klarna
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig_Env(t *testing.T) {
+	assertions := assert.New(t)
+
+	t.Setenv(UsernameEnv, "N123_abc")
+	t.Setenv(PasswordEnv, "secret")
+	t.Setenv(RegionEnv, "NA")
+	t.Setenv(EnvironmentEnv, "production")
+	t.Setenv(TimeoutEnv, "3s")
+
+	c, err := LoadConfig("")
+	assertions.Nil(err)
+	assertions.Equal("N123_abc", c.APIUsername)
+	assertions.Equal("secret", c.APIPassword)
+	assertions.Equal(RegionNA, c.Region)
+	assertions.Equal(Production, c.Environment)
+	assertions.Equal(3*time.Second, c.Timeout)
+}
+
+func TestLoadConfig_File(t *testing.T) {
+	assertions := assert.New(t)
+
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "klarna.yaml")
+	assertions.Nil(os.WriteFile(yamlPath, []byte(`
+username: PK123_abc
+password: secret
+environment: playground
+region: eu
+timeout: 2s
+reject_region_mismatch: true
+`), 0600))
+
+	c, err := LoadConfig(yamlPath)
+	assertions.Nil(err)
+	assertions.Equal("PK123_abc", c.APIUsername)
+	assertions.Equal(Playground, c.Environment)
+	assertions.Equal(RegionEU, c.Region)
+	assertions.Equal(2*time.Second, c.Timeout)
+	assertions.True(c.RejectRegionMismatch)
+
+	t.Setenv(PasswordEnv, "rotated")
+	jsonPath := filepath.Join(dir, "klarna.json")
+	content := `{"username":"K1_abc","password":"old","base_url":"https://api.klarna.com/"}`
+	assertions.Nil(os.WriteFile(jsonPath, []byte(content), 0600))
+
+	c, err = LoadConfig(jsonPath)
+	assertions.Nil(err)
+	assertions.Equal("rotated", c.APIPassword)
+	assertions.Equal(EuroAPI, c.BaseURL.String())
+
+	_, err = LoadConfig(filepath.Join(dir, "missing.yaml"))
+	assertions.ErrorIs(err, os.ErrNotExist)
+}
+
+func TestLoadConfig_AggregatedErrors(t *testing.T) {
+	assertions := assert.New(t)
+
+	t.Setenv(UsernameEnv, "K123_abc")
+	t.Setenv(RegionEnv, "na")
+	t.Setenv(TimeoutEnv, "10ms")
+
+	_, err := LoadConfig("")
+	assertions.ErrorIs(err, ErrNoCredentials)
+	assertions.ErrorIs(err, ErrRegionMismatch)
+	assertions.ErrorContains(err, "timeout 10ms is shorter than 100ms")
+}
+
+func TestConfig_ValidateRequiredFields(t *testing.T) {
+	assertions := assert.New(t)
+
+	err := Config{APIUsername: "someUser", APIPassword: "pass"}.Validate()
+	assertions.ErrorIs(err, ErrMissingRegion)
+
+	uri, _ := url.Parse("/ordermanagement")
+	err = Config{APIUsername: "someUser", APIPassword: "pass", BaseURL: uri}.Validate()
+	assertions.ErrorContains(err, "must be an absolute http or https URL")
+
+	err = Config{
+		APIUsername:       "K123_abc",
+		APIPassword:       "pass",
+		OperationTimeouts: map[string]time.Duration{"Checkout.RetrieveOrder": time.Hour},
+	}.Validate()
+	assertions.ErrorContains(err, "timeout of Checkout.RetrieveOrder 1h0m0s is longer than 10m0s")
+}
+
+func TestNewClient_RegionFromUsername(t *testing.T) {
+	assertions := assert.New(t)
+
+	c := NewClient(Config{APIUsername: "PN123_abc"}).(*client)
+	assertions.Nil(c.configErr)
+	assertions.Equal(PlaygroundUsAPI, c.config.BaseURL.String())
+
+	c = NewClient(Config{APIUsername: "someUser", Environment: Playground}).(*client)
+	assertions.ErrorIs(c.configErr, ErrMissingRegion)
+	_, err := NewCheckoutSrv(c).RetrieveOrder("abc")
+	assertions.ErrorIs(err, ErrMissingRegion)
+
+	c = NewClient(Config{APIUsername: "someUser", Environment: Playground, Region: RegionEU}).(*client)
+	assertions.Nil(c.configErr)
+	assertions.Equal(PlaygroundEuroAPI, c.config.BaseURL.String())
+
+	t.Setenv(UsernameEnv, "K123_abc")
+	t.Setenv(PasswordEnv, "pass")
+	c = NewClient(Config{Credentials: EnvCredentials("", "")}).(*client)
+	assertions.Nil(c.configErr)
+	assertions.Equal(EuroAPI, c.config.BaseURL.String())
+
+	t.Setenv(UsernameEnv, "N123_abc")
+	c = NewClient(Config{Credentials: EnvCredentials("", "")}).(*client)
+	assertions.Equal(UsAPI, c.config.BaseURL.String())
+}
@@ -0,0 +1,90 @@
+package go_klarna
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+const idempotencyKeyHeader = "Klarna-Idempotency-Key"
+
+// contextKey type is used for the values this package stores in a context.Context
+type contextKey int
+
+const (
+	idempotencyKeyContextKey contextKey = iota
+	operationContextKey
+	merchantContextKey
+)
+
+// boundKey type is an idempotency key carried by a context, it is bound to the first request it is sent with
+type boundKey struct {
+	key    string
+	mu     sync.Mutex
+	target string
+}
+
+// WithIdempotencyKey function returns a copy of the context carrying the given idempotency key, it is sent as
+// Klarna-Idempotency-Key header with the first POST or PATCH request executed with that context, and with every
+// later attempt of that same request. Other requests executed with the context do not carry it. Use it to make an
+// operation idempotent across process restarts, e.g. by deriving the key from your shipment id
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey, &boundKey{key: key})
+}
+
+// IdempotencyKeyFromContext function returns the idempotency key carried by the context, if there is any
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	if k, ok := ctx.Value(idempotencyKeyContextKey).(*boundKey); ok {
+		return k.key
+	}
+
+	return ""
+}
+
+// idempotencyKeyFor function returns the idempotency key of the context when it is unbound or bound to the given
+// request, empty otherwise. GET and DELETE requests never carry a key
+func idempotencyKeyFor(ctx context.Context, method, path string) string {
+	if http.MethodPost != method && http.MethodPatch != method {
+		return ""
+	}
+	k, ok := ctx.Value(idempotencyKeyContextKey).(*boundKey)
+	if !ok || "" == k.key {
+		return ""
+	}
+
+	target := method + " " + path
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if "" == k.target {
+		k.target = target
+	}
+	if target != k.target {
+		return ""
+	}
+
+	return k.key
+}
+
+// ensureIdempotencyKey function makes sure the context carries an idempotency key for the given request, a random
+// one is generated when the caller did not provide any or bound it to another request. The key stays the same for
+// every retry of the request
+func ensureIdempotencyKey(ctx context.Context, method, path string) context.Context {
+	key := idempotencyKeyFor(ctx, method, path)
+	if "" == key {
+		key = newUUID()
+	}
+
+	return WithIdempotencyKey(ctx, key)
+}
+
+// newUUID function generates a random (version 4) UUID
+func newUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
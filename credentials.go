@@ -0,0 +1,207 @@
+package go_klarna
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// UsernameEnv is the environment variable EnvCredentials reads the API username from by default
+	UsernameEnv = "KLARNA_USERNAME"
+	// PasswordEnv is the environment variable EnvCredentials reads the API password from by default
+	PasswordEnv = "KLARNA_PASSWORD"
+)
+
+// ErrNoCredentials error describes a credentials provider that has no API username or password to hand out
+var ErrNoCredentials = errors.New("no Klarna API credentials available")
+
+type (
+	// Credentials type holds the API username and password requests are authenticated with
+	Credentials struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	// CredentialsProvider type is asked for the credentials before every request sent to Klarna, it must be safe for
+	// concurrent use. Rotating the API keys only requires the provider to hand out the new ones
+	CredentialsProvider interface {
+		Credentials(ctx context.Context) (Credentials, error)
+	}
+
+	// staticCredentials type hands out the same credentials forever
+	staticCredentials Credentials
+
+	// envCredentials type reads the credentials from the environment on every call
+	envCredentials struct {
+		usernameVar string
+		passwordVar string
+	}
+
+	// fileCredentials type reads the credentials from a JSON file and reloads them whenever the file changes
+	fileCredentials struct {
+		path string
+
+		mu      sync.Mutex
+		modTime time.Time
+		size    int64
+		creds   Credentials
+	}
+
+	// cachedCredentials type keeps the credentials of another provider for a while
+	cachedCredentials struct {
+		provider CredentialsProvider
+		ttl      time.Duration
+
+		mu      sync.Mutex
+		creds   Credentials
+		expires time.Time
+	}
+
+	// invalidator type is implemented by the providers holding on to credentials, the client invalidates them as soon
+	// as Klarna rejects them so rotated keys are picked up right away
+	invalidator interface {
+		Invalidate()
+	}
+)
+
+// StaticCredentials function returns a provider handing out the given username and password, it is what the client
+// uses for Config.APIUsername and Config.APIPassword
+func StaticCredentials(username, password string) CredentialsProvider {
+	return staticCredentials{Username: username, Password: password}
+}
+
+// EnvCredentials function returns a provider reading the username and password from the given environment variables
+// on every request, empty names default to KLARNA_USERNAME and KLARNA_PASSWORD
+func EnvCredentials(usernameVar, passwordVar string) CredentialsProvider {
+	if "" == usernameVar {
+		usernameVar = UsernameEnv
+	}
+	if "" == passwordVar {
+		passwordVar = PasswordEnv
+	}
+
+	return &envCredentials{usernameVar: usernameVar, passwordVar: passwordVar}
+}
+
+// FileCredentials function returns a provider reading the credentials from a JSON file holding a username and a
+// password, e.g. a mounted secret. The file is watched for changes and reloaded once it is modified
+func FileCredentials(path string) CredentialsProvider {
+	return &fileCredentials{path: path}
+}
+
+// CachedCredentials function returns a provider asking the given one at most once per ttl, the cached credentials
+// are dropped early when Klarna rejects them
+func CachedCredentials(provider CredentialsProvider, ttl time.Duration) CredentialsProvider {
+	return &cachedCredentials{provider: provider, ttl: ttl}
+}
+
+// Credentials method returns the static credentials
+func (s staticCredentials) Credentials(context.Context) (Credentials, error) {
+	return Credentials(s), nil
+}
+
+// Credentials method returns the credentials currently set in the environment
+func (e *envCredentials) Credentials(context.Context) (Credentials, error) {
+	creds, err := Credentials{
+		Username: os.Getenv(e.usernameVar),
+		Password: os.Getenv(e.passwordVar),
+	}.check()
+	if nil != err {
+		return creds, fmt.Errorf("%w: set %s and %s", err, e.usernameVar, e.passwordVar)
+	}
+
+	return creds, nil
+}
+
+// Credentials method returns the credentials of the file, it is only read again when its modification time or size
+// changed since the last call
+func (f *fileCredentials) Credentials(context.Context) (Credentials, error) {
+	info, err := os.Stat(f.path)
+	if nil != err {
+		return Credentials{}, fmt.Errorf("klarna credentials file: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if "" != f.creds.Username && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.creds, nil
+	}
+
+	content, err := os.ReadFile(f.path)
+	if nil != err {
+		return Credentials{}, fmt.Errorf("klarna credentials file: %w", err)
+	}
+	var creds Credentials
+	if err = json.Unmarshal(content, &creds); nil != err {
+		return Credentials{}, fmt.Errorf("klarna credentials file %s: %w", f.path, err)
+	}
+	if creds, err = creds.check(); nil != err {
+		return creds, fmt.Errorf("%w in %s", err, f.path)
+	}
+
+	f.creds, f.modTime, f.size = creds, info.ModTime(), info.Size()
+
+	return creds, nil
+}
+
+// Invalidate method makes the next call read the file again
+func (f *fileCredentials) Invalidate() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.creds = Credentials{}
+}
+
+// Credentials method returns the cached credentials, or asks the underlying provider once they expired
+func (c *cachedCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if "" != c.creds.Username && time.Now().Before(c.expires) {
+		return c.creds, nil
+	}
+
+	creds, err := c.provider.Credentials(ctx)
+	if nil != err {
+		return creds, err
+	}
+	c.creds, c.expires = creds, time.Now().Add(c.ttl)
+
+	return creds, nil
+}
+
+// Invalidate method drops the cached credentials, along with the ones of the underlying provider
+func (c *cachedCredentials) Invalidate() {
+	c.mu.Lock()
+	c.creds = Credentials{}
+	c.mu.Unlock()
+
+	if inv, ok := c.provider.(invalidator); ok {
+		inv.Invalidate()
+	}
+}
+
+// check method makes sure both the username and the password are set
+func (c Credentials) check() (Credentials, error) {
+	if "" == c.Username || "" == c.Password {
+		return c, ErrNoCredentials
+	}
+
+	return c, nil
+}
+
+// credentialsProvider method returns the provider of the configuration, falling back to the static API username and
+// password
+func (c Config) credentialsProvider() CredentialsProvider {
+	if nil != c.Credentials {
+		return c.Credentials
+	}
+
+	return StaticCredentials(c.APIUsername, c.APIPassword)
+}
@@ -0,0 +1,146 @@
+package go_klarna
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingCredentials struct {
+	calls    int32
+	username atomic.Value
+}
+
+func (c *countingCredentials) Credentials(context.Context) (Credentials, error) {
+	atomic.AddInt32(&c.calls, 1)
+
+	return Credentials{Username: c.username.Load().(string), Password: "somePass"}, nil
+}
+
+func TestEnvCredentials(t *testing.T) {
+	assertions := assert.New(t)
+
+	t.Setenv(UsernameEnv, "K123_abc")
+	t.Setenv(PasswordEnv, "secret")
+	creds, err := EnvCredentials("", "").Credentials(context.Background())
+	assertions.Nil(err)
+	assertions.Equal(Credentials{Username: "K123_abc", Password: "secret"}, creds)
+
+	t.Setenv(PasswordEnv, "")
+	_, err = EnvCredentials("", "").Credentials(context.Background())
+	assertions.ErrorIs(err, ErrNoCredentials)
+}
+
+func TestFileCredentials_Reload(t *testing.T) {
+	assertions := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "klarna.json")
+	assertions.Nil(os.WriteFile(path, []byte(`{"username":"K1_old","password":"old"}`), 0600))
+
+	provider := FileCredentials(path)
+	creds, err := provider.Credentials(context.Background())
+	assertions.Nil(err)
+	assertions.Equal("K1_old", creds.Username)
+
+	assertions.Nil(os.WriteFile(path, []byte(`{"username":"K1_rotated","password":"new"}`), 0600))
+	assertions.Nil(os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	creds, err = provider.Credentials(context.Background())
+	assertions.Nil(err)
+	assertions.Equal(Credentials{Username: "K1_rotated", Password: "new"}, creds)
+
+	assertions.Nil(os.WriteFile(path, []byte(`{"username":"K1_rotated"}`), 0600))
+	assertions.Nil(os.Chtimes(path, time.Now(), time.Now().Add(time.Hour)))
+	_, err = provider.Credentials(context.Background())
+	assertions.ErrorIs(err, ErrNoCredentials)
+
+	_, err = FileCredentials(filepath.Join(t.TempDir(), "missing.json")).Credentials(context.Background())
+	assertions.ErrorIs(err, os.ErrNotExist)
+}
+
+func TestCachedCredentials(t *testing.T) {
+	assertions := assert.New(t)
+
+	inner := &countingCredentials{}
+	inner.username.Store("K1_abc")
+	provider := CachedCredentials(inner, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		creds, err := provider.Credentials(context.Background())
+		assertions.Nil(err)
+		assertions.Equal("K1_abc", creds.Username)
+	}
+	assertions.Equal(int32(1), atomic.LoadInt32(&inner.calls))
+
+	provider.(invalidator).Invalidate()
+	_, _ = provider.Credentials(context.Background())
+	assertions.Equal(int32(2), atomic.LoadInt32(&inner.calls))
+}
+
+func TestClient_RotatedCredentials(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc", func(w http.ResponseWriter, r *http.Request) {
+		if user, _, _ := r.BasicAuth(); "K1_new" != user {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"order_id":"abc"}`))
+	})
+
+	inner := &countingCredentials{}
+	inner.username.Store("K1_old")
+	uri, _ := url.Parse(testingServer.URL)
+	srv := NewOrderManagement(NewClient(Config{
+		BaseURL:     uri,
+		Credentials: CachedCredentials(inner, time.Hour),
+	}))
+
+	_, err := srv.GetOrder("abc")
+	assertions.ErrorIs(err, ErrUnAuthorized)
+
+	inner.username.Store("K1_new")
+	order, err := srv.GetOrder("abc")
+	assertions.Nil(err)
+	assertions.Equal("abc", order.ID)
+	assertions.Equal(int32(2), atomic.LoadInt32(&inner.calls))
+}
+
+func TestClient_RejectRegionMismatchPerRequest(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"order_id":"abc"}`))
+	})
+
+	inner := &countingCredentials{}
+	inner.username.Store("K1_eu")
+	srv := NewOrderManagement(NewClient(Config{
+		BaseURL:              &url.URL{Scheme: "https", Host: "api.klarna.com"},
+		Credentials:          inner,
+		RejectRegionMismatch: true,
+		HTTPClient:           testingServer.Client(),
+		Middleware: []Middleware{func(next Handler) Handler {
+			return func(op Operation, req *http.Request) (*http.Response, error) {
+				req.URL.Scheme, req.URL.Host = "http", testingServer.Listener.Addr().String()
+				return next(op, req)
+			}
+		}},
+	}))
+
+	_, err := srv.GetOrder("abc")
+	assertions.Nil(err)
+
+	inner.username.Store("N1_na")
+	_, err = srv.GetOrder("abc")
+	assertions.ErrorIs(err, ErrRegionMismatch)
+}
@@ -0,0 +1,331 @@
+package go_klarna
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// DryRun type keeps the mutating calls a client did not send to Klarna, see Config.DryRun. The captures and refunds
+	// it created are answered when fetched, so CaptureOrderAndGet and RefundOrderAndGet work as well. It is safe for
+	// concurrent use and can be shared by several clients
+	DryRun struct {
+		mu      sync.Mutex
+		records []DryRunRecord
+		// created holds the resources created by the dry run by their path
+		created map[string][]byte
+	}
+
+	// DryRunRecord type describes a mutating call answered by the dry run instead of Klarna
+	DryRunRecord struct {
+		Operation      Operation
+		Method         string
+		Path           string
+		Body           json.RawMessage
+		IdempotencyKey string
+		// StatusCode is the status of the synthetic response
+		StatusCode int
+		// Problems lists why the request was rejected, it is empty for a request Klarna would presumably accept
+		Problems []string
+		At       time.Time
+	}
+)
+
+// NewDryRun factory method
+func NewDryRun() *DryRun {
+	return &DryRun{}
+}
+
+// Records method returns the calls recorded so far, in the order they were made
+func (d *DryRun) Records() []DryRunRecord {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]DryRunRecord(nil), d.records...)
+}
+
+// Reset method drops the calls recorded so far and the resources they created
+func (d *DryRun) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.records = nil
+	d.created = nil
+}
+
+// add method records a call
+func (d *DryRun) add(r DryRunRecord) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.records = append(d.records, r)
+}
+
+// keep method remembers a resource created by the dry run
+func (d *DryRun) keep(path string, resource []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if nil == d.created {
+		d.created = make(map[string][]byte)
+	}
+	d.created[path] = resource
+}
+
+// resource method returns a resource created by the dry run
+func (d *DryRun) resource(path string) ([]byte, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	resource, ok := d.created[path]
+
+	return resource, ok
+}
+
+// dryRun method is the internal middleware answering the mutating order management and payments calls, and the
+// fetches of the resources they created, on behalf of Klarna. Every other request is passed on
+func (c *client) dryRun(next Handler) Handler {
+	return func(op Operation, req *http.Request) (*http.Response, error) {
+		if http.MethodGet == req.Method {
+			if resource, ok := c.config.DryRun.resource(req.URL.Path); ok {
+				return newSyntheticResponse(req, http.StatusOK, dryRunHeader(), resource), nil
+			}
+			return next(op, req)
+		}
+		if !isDryRunFamily(op.Family()) {
+			return next(op, req)
+		}
+
+		var body []byte
+		if nil != req.Body {
+			b, err := io.ReadAll(req.Body)
+			_ = req.Body.Close()
+			if nil != err {
+				return nil, err
+			}
+			body = b
+		}
+
+		record := DryRunRecord{
+			Operation:      op,
+			Method:         req.Method,
+			Path:           req.URL.Path,
+			IdempotencyKey: req.Header.Get(idempotencyKeyHeader),
+			Problems:       validateDryRun(op, body),
+			At:             time.Now(),
+		}
+		if 0 < len(body) {
+			record.Body = json.RawMessage(body)
+		}
+
+		res := syntheticResponse(c.config.DryRun, op, req, body, record.Problems)
+		record.StatusCode = res.StatusCode
+		c.config.DryRun.add(record)
+
+		return res, nil
+	}
+}
+
+// isDryRunFamily function tells whether the mutating calls of an endpoint family are held back in dry run mode
+func isDryRunFamily(family EndpointFamily) bool {
+	return OrderManagementFamily == family || PaymentsFamily == family
+}
+
+// validateDryRun function runs the checks Klarna would run on the body of a mutating call and returns the problems
+// found, only the common mistakes are caught: malformed JSON, missing amounts and amounts not matching the lines
+func validateDryRun(op Operation, body []byte) []string {
+	if 0 == len(body) || "null" == string(bytes.TrimSpace(body)) {
+		return nil
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); nil != err {
+		return []string{fmt.Sprintf("body is not a JSON object: %s", err)}
+	}
+
+	var problems []string
+	switch op.Name {
+	case "OrderManagement.CreateCapture":
+		problems = append(problems, positiveAmount(payload, "captured_amount")...)
+		problems = append(problems, linesMatch(payload, "captured_amount")...)
+	case "OrderManagement.CreateRefund":
+		problems = append(problems, positiveAmount(payload, "refund_amount")...)
+		problems = append(problems, linesMatch(payload, "refund_amount")...)
+	case "OrderManagement.SetOrderAmountLines":
+		problems = append(problems, linesMatch(payload, "order_amount")...)
+	case "Payment.CreateNewSession", "Payment.UpdateExistingSession", "Payment.CreateNewOrder":
+		for _, field := range []string{"purchase_country", "purchase_currency", "locale"} {
+			if "" == stringField(payload, field) {
+				problems = append(problems, field+" is required")
+			}
+		}
+		problems = append(problems, linesMatch(payload, "order_amount")...)
+	}
+
+	return problems
+}
+
+// positiveAmount function checks that the amount field of the payload is greater than zero
+func positiveAmount(payload map[string]json.RawMessage, field string) []string {
+	if 0 >= intField(payload, field) {
+		return []string{field + " must be greater than zero"}
+	}
+
+	return nil
+}
+
+// linesMatch function checks that the amount field of the payload equals the sum of its order lines, a payload
+// without order lines is not checked
+func linesMatch(payload map[string]json.RawMessage, field string) []string {
+	var lines []struct {
+		TotalAmount int `json:"total_amount"`
+	}
+	if err := json.Unmarshal(payload["order_lines"], &lines); nil != err || 0 == len(lines) {
+		return nil
+	}
+
+	sum := 0
+	for _, l := range lines {
+		sum += l.TotalAmount
+	}
+	if amount := intField(payload, field); sum != amount {
+		return []string{fmt.Sprintf("%s %d does not match the order lines total of %d", field, amount, sum)}
+	}
+
+	return nil
+}
+
+// intField function returns the integer value of a field of the payload, zero when it is missing or not a number
+func intField(payload map[string]json.RawMessage, field string) int {
+	var v int
+	_ = json.Unmarshal(payload[field], &v)
+
+	return v
+}
+
+// stringField function returns the string value of a field of the payload, empty when it is missing or not a string
+func stringField(payload map[string]json.RawMessage, field string) string {
+	var v string
+	_ = json.Unmarshal(payload[field], &v)
+
+	return v
+}
+
+// syntheticResponse function builds the answer Klarna would give to the call, a 400 BAD_VALUE error when problems
+// were found. The captures and refunds created are kept by the dry run
+func syntheticResponse(d *DryRun, op Operation, req *http.Request, payload []byte, problems []string) *http.Response {
+	header := dryRunHeader()
+
+	status := http.StatusNoContent
+	var body interface{}
+	switch {
+	case 0 < len(problems):
+		status = http.StatusBadRequest
+		body = map[string]interface{}{
+			"error_code":     ErrorCodeBadValue,
+			"error_messages": problems,
+			"correlation_id": header.Get(correlationIDHeader),
+		}
+	case "OrderManagement.CreateCapture" == op.Name:
+		status = http.StatusCreated
+		id := newUUID()
+		header.Set(captureIDHeader, id)
+		header.Set("Location", locationOf(req, id))
+		d.keep(resourcePath(req, id), createdResource(payload, "capture_id", id, "captured_at", map[string]string{
+			"captured_amount": "capture_amount",
+		}))
+	case "OrderManagement.CreateRefund" == op.Name:
+		status = http.StatusCreated
+		id := newUUID()
+		header.Set(refundIDHeader, id)
+		header.Set("Location", locationOf(req, id))
+		d.keep(resourcePath(req, id), createdResource(payload, "refund_id", id, "refunded_at", map[string]string{
+			"refund_amount": "refunded_amount",
+		}))
+	case "Payment.CreateNewSession" == op.Name:
+		status = http.StatusOK
+		body = PaymentSession{SessionID: newUUID(), ClientToken: "dry-run-" + newUUID()}
+	case "Payment.CreateNewOrder" == op.Name:
+		status = http.StatusOK
+		id := newUUID()
+		body = PaymentOrderInfo{
+			OrderID:     id,
+			RedirectURL: "https://dry-run.invalid/orders/" + id,
+			FraudStatus: Accepted,
+		}
+	}
+
+	var content []byte
+	if nil != body {
+		content, _ = json.Marshal(body)
+	}
+
+	return newSyntheticResponse(req, status, header, content)
+}
+
+// dryRunHeader function returns the headers every synthetic response carries
+func dryRunHeader() http.Header {
+	header := make(http.Header)
+	header.Set(correlationIDHeader, "dry-run-"+newUUID())
+
+	return header
+}
+
+// newSyntheticResponse function wraps the status, headers and JSON content into a response
+func newSyntheticResponse(req *http.Request, status int, header http.Header, content []byte) *http.Response {
+	if nil != content {
+		header.Set("Content-Type", "application/json")
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(content)),
+		ContentLength: int64(len(content)),
+		Request:       req,
+	}
+}
+
+// locationOf function returns the URL of a resource created under the path of the request
+func locationOf(req *http.Request, id string) string {
+	u := *req.URL
+	u.Path = resourcePath(req, id)
+	u.RawPath = ""
+
+	return u.String()
+}
+
+// resourcePath function returns the path of a resource created under the path of the request
+func resourcePath(req *http.Request, id string) string {
+	return strings.TrimRight(req.URL.Path, "/") + "/" + id
+}
+
+// createdResource function builds the resource a GET of a created capture or refund is answered with out of the
+// body it was created with: the id and creation time are added and the requested amount becomes the booked one
+func createdResource(payload []byte, idField, id, atField string, renames map[string]string) []byte {
+	resource := make(map[string]interface{})
+	_ = json.Unmarshal(payload, &resource)
+	for from, to := range renames {
+		if v, ok := resource[from]; ok {
+			resource[to] = v
+			delete(resource, from)
+		}
+	}
+	resource[idField] = id
+	resource[atField] = time.Now().UTC().Format(time.RFC3339)
+
+	content, _ := json.Marshal(resource)
+
+	return content
+}
@@ -0,0 +1,155 @@
+package go_klarna
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func dryRunClient(dr *DryRun) Client {
+	uri, _ := url.Parse(testingServer.URL)
+
+	return NewClient(Config{
+		BaseURL:     uri,
+		APIUsername: "someUser",
+		APIPassword: "somePass",
+		DryRun:      dr,
+	})
+}
+
+func TestDryRun_OrderManagement(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	sent := 0
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc/", func(w http.ResponseWriter, r *http.Request) {
+		sent++
+	})
+	setupMux(assertions, "/ordermanagement/v1/orders/abc", nil, http.MethodGet, &OrderManagementOrder{ID: "abc"})
+
+	dr := NewDryRun()
+	srv := NewOrderManagement(dryRunClient(dr))
+
+	order, err := srv.GetOrder("abc")
+	assertions.Nil(err)
+	assertions.Equal("abc", order.ID)
+
+	cid, err := srv.CaptureOrderContext(context.Background(), "abc", &CreateCapture{
+		CapturedAmount: 1000,
+		OrderLines:     []*Line{{TotalAmount: 1000}},
+	})
+	assertions.Nil(err)
+	assertions.NotEmpty(cid)
+	assertions.Nil(srv.CancelOrder("abc"))
+	assertions.Equal(0, sent)
+
+	records := dr.Records()
+	assertions.Len(records, 2)
+	assertions.Equal("OrderManagement.CreateCapture", records[0].Operation.Name)
+	assertions.Equal("/ordermanagement/v1/orders/abc/captures", records[0].Path)
+	assertions.Equal(http.StatusCreated, records[0].StatusCode)
+	assertions.NotEmpty(records[0].IdempotencyKey)
+	assertions.Contains(string(records[0].Body), `"captured_amount":1000`)
+	assertions.Equal("OrderManagement.CancelOrder", records[1].Operation.Name)
+	assertions.Equal(http.StatusNoContent, records[1].StatusCode)
+
+	dr.Reset()
+	assertions.Empty(dr.Records())
+}
+
+func TestDryRun_CreatedResources(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	sent := 0
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc/", func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	dr := NewDryRun()
+	srv := NewOrderManagement(dryRunClient(dr))
+
+	capture, err := srv.CaptureOrderAndGetContext(context.Background(), "abc", &CreateCapture{
+		CapturedAmount: 1000,
+		Description:    "first shipment",
+	})
+	assertions.Nil(err)
+	assertions.NotEmpty(capture.ID)
+	assertions.Equal(1000, capture.CaptureAmount)
+	assertions.Equal("first shipment", capture.Description)
+	assertions.NotEmpty(capture.CapturedAt)
+
+	refund, err := srv.RefundOrderAndGetContext(context.Background(), "abc", &OrderManagementRefund{RefundAmount: 500})
+	assertions.Nil(err)
+	assertions.NotEmpty(refund.ID)
+	assertions.Equal(500, refund.RefundedAmount)
+	assertions.Equal(0, sent)
+	assertions.Len(dr.Records(), 2)
+
+	dr.Reset()
+	_, err = srv.GetCapture("abc", capture.ID)
+	assertions.ErrorIs(err, ErrOrderNotFound)
+	assertions.Equal(1, sent)
+}
+
+func TestDryRun_Validation(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	dr := NewDryRun()
+	srv := NewOrderManagement(dryRunClient(dr))
+
+	err := srv.CreateCapture("abc", &CreateCapture{
+		CapturedAmount: 1000,
+		OrderLines:     []*Line{{TotalAmount: 900}},
+	})
+	assertions.ErrorIs(err, ErrBadRequest)
+	assertions.True(HasErrorCode(err, ErrorCodeBadValue))
+
+	err = srv.CreateRefund("abc", &OrderManagementRefund{})
+	assertions.ErrorIs(err, ErrBadRequest)
+
+	records := dr.Records()
+	assertions.Len(records, 2)
+	assertions.Equal(http.StatusBadRequest, records[0].StatusCode)
+	assertions.Equal([]string{"captured_amount 1000 does not match the order lines total of 900"}, records[0].Problems)
+	assertions.Equal([]string{"refund_amount must be greater than zero"}, records[1].Problems)
+}
+
+func TestDryRun_Payments(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	dr := NewDryRun()
+	srv := NewPaymentSrv(dryRunClient(dr))
+
+	order := &PaymentOrder{
+		PurchaseCountry:  "DE",
+		PurchaseCurrency: "EUR",
+		Locale:           "de-DE",
+		OrderAmount:      500,
+		OrderLines:       []*Line{{TotalAmount: 500}},
+	}
+	session, err := srv.CreateNewSession(order)
+	assertions.Nil(err)
+	assertions.NotEmpty(session.SessionID)
+	assertions.NotEmpty(session.ClientToken)
+
+	info, err := srv.CreateNewOrder("token", order)
+	assertions.Nil(err)
+	assertions.NotEmpty(info.OrderID)
+	assertions.Equal(Accepted, info.FraudStatus)
+
+	assertions.Nil(srv.CancelExistingAuthorization("token"))
+
+	_, err = srv.CreateNewSession(&PaymentOrder{})
+	assertions.ErrorIs(err, ErrBadRequest)
+	assertions.Len(dr.Records(), 4)
+}
@@ -0,0 +1,142 @@
+package go_klarna
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	OceaniaAPI           = "https://api-oc.klarna.com/"
+	PlaygroundEuroAPI    = "https://api.playground.klarna.com/"
+	PlaygroundUsAPI      = "https://api-na.playground.klarna.com/"
+	PlaygroundOceaniaAPI = "https://api-oc.playground.klarna.com/"
+
+	// Production environment moves real money, Playground is Klarna's test environment
+	Production Environment = "production"
+	Playground Environment = "playground"
+
+	// Klarna API regions, each one of them has its own set of credentials
+	RegionEU Region = "eu"
+	RegionNA Region = "na"
+	RegionOC Region = "oc"
+)
+
+var (
+	// ErrUnknownRegion error describes a region or environment which has no known Klarna API endpoint
+	ErrUnknownRegion = errors.New("unknown Klarna API region or environment")
+	// ErrRegionMismatch error describes API credentials used against the endpoint of another region or environment
+	ErrRegionMismatch = errors.New("API credentials do not belong to the region or environment of the endpoint")
+
+	baseURLs = map[Environment]map[Region]string{
+		Production: {
+			RegionEU: EuroAPI,
+			RegionNA: UsAPI,
+			RegionOC: OceaniaAPI,
+		},
+		Playground: {
+			RegionEU: PlaygroundEuroAPI,
+			RegionNA: PlaygroundUsAPI,
+			RegionOC: PlaygroundOceaniaAPI,
+		},
+	}
+
+	// usernamePrefixes maps the first letter of a Klarna API username (merchant id) to the region it belongs to,
+	// playground usernames carry an additional "P" in front of it
+	usernamePrefixes = map[byte]Region{
+		'K': RegionEU,
+		'N': RegionNA,
+		'M': RegionOC,
+	}
+)
+
+type (
+	// Environment type selects between Klarna's production and playground APIs
+	Environment string
+
+	// Region type selects the regional Klarna API, Europe, North America or Oceania
+	Region string
+)
+
+// BaseURLFor function resolves the Klarna API base URL of the given environment and region, empty values default to
+// Production and RegionEU
+func BaseURLFor(env Environment, region Region) (*url.URL, error) {
+	if "" == env {
+		env = Production
+	}
+	if "" == region {
+		region = RegionEU
+	}
+
+	raw, ok := baseURLs[env][region]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q in %q", ErrUnknownRegion, region, env)
+	}
+
+	return url.Parse(raw)
+}
+
+// EndpointOf function tells the environment and region of a Klarna API base URL, ok is false when the URL is not a
+// known Klarna endpoint, e.g. a proxy or a test server
+func EndpointOf(u *url.URL) (env Environment, region Region, ok bool) {
+	if nil == u {
+		return "", "", false
+	}
+	for e, regions := range baseURLs {
+		for r, raw := range regions {
+			known, _ := url.Parse(raw)
+			if strings.EqualFold(known.Host, u.Host) {
+				return e, r, true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+// CredentialsRegion function tells the environment and region a Klarna API username belongs to, ok is false when the
+// username does not follow Klarna's merchant id format
+func CredentialsRegion(username string) (env Environment, region Region, ok bool) {
+	env = Production
+	if 1 < len(username) && 'P' == username[0] {
+		if _, known := usernamePrefixes[username[1]]; known {
+			env = Playground
+			username = username[1:]
+		}
+	}
+	if "" == username {
+		return "", "", false
+	}
+
+	region, ok = usernamePrefixes[username[0]]
+	if !ok {
+		return "", "", false
+	}
+
+	return env, region, true
+}
+
+// checkRegion function verifies that the username belongs to the environment and region of the base URL, unknown
+// usernames and endpoints are accepted as there is nothing to compare them with
+func checkRegion(username string, baseURL *url.URL) error {
+	credEnv, credRegion, ok := CredentialsRegion(username)
+	if !ok {
+		return nil
+	}
+	env, region, ok := EndpointOf(baseURL)
+	if !ok {
+		return nil
+	}
+	if credEnv != env || credRegion != region {
+		return fmt.Errorf(
+			"%w: username of %s %s used against %s %s (%s)",
+			ErrRegionMismatch,
+			credEnv, strings.ToUpper(string(credRegion)),
+			env, strings.ToUpper(string(region)),
+			baseURL.Host,
+		)
+	}
+
+	return nil
+}
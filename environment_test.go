@@ -0,0 +1,88 @@
+package go_klarna
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func TestBaseURLFor(t *testing.T) {
+	assertions := assert.New(t)
+
+	cases := []struct {
+		env      Environment
+		region   Region
+		expected string
+	}{
+		{"", "", EuroAPI},
+		{Production, RegionNA, UsAPI},
+		{Production, RegionOC, OceaniaAPI},
+		{Playground, "", PlaygroundEuroAPI},
+		{Playground, RegionNA, PlaygroundUsAPI},
+		{Playground, RegionOC, PlaygroundOceaniaAPI},
+	}
+	for _, tc := range cases {
+		uri, err := BaseURLFor(tc.env, tc.region)
+		assertions.Nil(err)
+		assertions.Equal(tc.expected, uri.String())
+	}
+
+	_, err := BaseURLFor(Production, "apac")
+	assertions.ErrorIs(err, ErrUnknownRegion)
+}
+
+func TestCredentialsRegion(t *testing.T) {
+	assertions := assert.New(t)
+
+	env, region, ok := CredentialsRegion("K123456_abcdef")
+	assertions.True(ok)
+	assertions.Equal(Production, env)
+	assertions.Equal(RegionEU, region)
+
+	env, region, ok = CredentialsRegion("PN00123_abcdef")
+	assertions.True(ok)
+	assertions.Equal(Playground, env)
+	assertions.Equal(RegionNA, region)
+
+	_, _, ok = CredentialsRegion("someUser")
+	assertions.False(ok)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assertions := assert.New(t)
+
+	assertions.Nil(Config{APIUsername: "PK123_abc", APIPassword: "pass", Environment: Playground}.Validate())
+	assertions.Nil(Config{APIUsername: "M123_abc", APIPassword: "pass", Region: RegionOC}.Validate())
+	assertions.Nil(Config{APIUsername: "PN123_abc", APIPassword: "pass"}.Validate())
+	assertions.ErrorIs(
+		Config{APIUsername: "K123_abc", APIPassword: "pass", Region: RegionNA}.Validate(),
+		ErrRegionMismatch,
+	)
+	assertions.ErrorIs(
+		Config{APIUsername: "PK123_abc", APIPassword: "pass", Region: RegionEU}.Validate(),
+		ErrRegionMismatch,
+	)
+	assertions.ErrorIs(Config{Region: "apac"}.Validate(), ErrUnknownRegion)
+
+	uri, _ := url.Parse("http://localhost:8080")
+	assertions.Nil(Config{APIUsername: "K123_abc", APIPassword: "pass", BaseURL: uri}.Validate())
+}
+
+func TestNewClient_ResolvesBaseURL(t *testing.T) {
+	assertions := assert.New(t)
+
+	c := NewClient(Config{Environment: Playground, Region: RegionNA}).(*client)
+	assertions.Equal(PlaygroundUsAPI, c.config.BaseURL.String())
+}
+
+func TestNewClient_RejectRegionMismatch(t *testing.T) {
+	assertions := assert.New(t)
+
+	c := NewClient(Config{APIUsername: "K123_abc", Region: RegionNA, RejectRegionMismatch: true})
+	_, err := c.Get("/ordermanagement/v1/orders/abc")
+	assertions.ErrorIs(err, ErrRegionMismatch)
+
+	c = NewClient(Config{Region: "apac"})
+	_, err = c.Get("/ordermanagement/v1/orders/abc")
+	assertions.ErrorIs(err, ErrUnknownRegion)
+}
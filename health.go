@@ -0,0 +1,139 @@
+package go_klarna
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+const (
+	// FailureAuth describes credentials rejected by Klarna or missing altogether
+	FailureAuth FailureKind = "auth"
+	// FailureConfig describes a configuration the client can not work with, e.g. an unknown region
+	FailureConfig FailureKind = "config"
+	// FailureDNS describes a Klarna API host name that could not be resolved
+	FailureDNS FailureKind = "dns"
+	// FailureTLS describes a failed TLS handshake, e.g. an untrusted certificate
+	FailureTLS FailureKind = "tls"
+	// FailureTimeout describes a check that did not complete in time
+	FailureTimeout FailureKind = "timeout"
+	// FailureNetwork describes any other failure to reach Klarna, e.g. a refused connection
+	FailureNetwork FailureKind = "network"
+	// FailureOutage describes Klarna being unavailable: 5xx answers, throttling or an open circuit breaker
+	FailureOutage FailureKind = "outage"
+	// FailureUnknown describes an answer of Klarna the check does not expect
+	FailureUnknown FailureKind = "unknown"
+)
+
+type (
+	// FailureKind type tells why a health check failed
+	FailureKind string
+
+	// HealthError type is returned by a failed health check
+	HealthError struct {
+		Kind FailureKind
+		Err  error
+	}
+)
+
+// Error method returns the string representation of the failed check
+func (e *HealthError) Error() string {
+	return fmt.Sprintf("klarna health check failed (%s): %s", e.Kind, e.Err)
+}
+
+// Unwrap method returns the error the check failed with
+func (e *HealthError) Unwrap() error {
+	return e.Err
+}
+
+// Ping function checks the credentials and region of the client, and the connectivity to Klarna, with a cheap
+// authenticated call: an order management lookup of an order that does not exist. Klarna answering that the order
+// is not found proves the credentials are accepted. A failed check returns a *HealthError telling why
+func Ping(ctx context.Context, c Client) error {
+	_, err := send(ctx, c, request{
+		op:     "Health.Ping",
+		method: http.MethodGet,
+		route:  OrderManagementEndpoint + "/{order_id}",
+		params: []string{"health-check-" + newUUID()},
+	})
+	if nil == err || errors.Is(err, ErrOrderNotFound) {
+		return nil
+	}
+
+	return &HealthError{Kind: failureKindOf(err), Err: err}
+}
+
+// HealthHandler function returns an http.Handler answering 200 when Ping succeeds and 503 with the reason otherwise,
+// ready to be used as readiness probe. The check is bound to the context of the probe request
+func HealthHandler(c Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := struct {
+			Status  string      `json:"status"`
+			Failure FailureKind `json:"failure,omitempty"`
+			Error   string      `json:"error,omitempty"`
+		}{Status: "ok"}
+		code := http.StatusOK
+
+		var healthErr *HealthError
+		if err := Ping(r.Context(), c); errors.As(err, &healthErr) {
+			status.Status, status.Failure, status.Error = "unavailable", healthErr.Kind, healthErr.Err.Error()
+			code = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(status)
+	})
+}
+
+// failureKindOf function classifies the error a health check failed with
+func failureKindOf(err error) FailureKind {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case http.StatusUnauthorized == apiErr.StatusCode, http.StatusForbidden == apiErr.StatusCode:
+			return FailureAuth
+		case http.StatusTooManyRequests == apiErr.StatusCode, http.StatusInternalServerError <= apiErr.StatusCode:
+			return FailureOutage
+		default:
+			return FailureUnknown
+		}
+	}
+
+	var (
+		dnsErr       *net.DNSError
+		recordErr    tls.RecordHeaderError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+		netErr       net.Error
+	)
+	switch {
+	case errors.Is(err, ErrNoCredentials):
+		return FailureAuth
+	case errors.Is(err, ErrRegionMismatch), errors.Is(err, ErrUnknownRegion), errors.Is(err, ErrMissingRegion):
+		return FailureConfig
+	case errors.Is(err, ErrCircuitOpen):
+		return FailureOutage
+	case errors.Is(err, context.DeadlineExceeded):
+		return FailureTimeout
+	case errors.As(err, &dnsErr):
+		return FailureDNS
+	case errors.As(err, &recordErr), errors.As(err, &verifyErr), errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return FailureTLS
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return FailureTimeout
+	case errors.As(err, &netErr):
+		return FailureNetwork
+	}
+
+	return FailureUnknown
+}
@@ -0,0 +1,105 @@
+package go_klarna
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func healthClient(rawURL string, timeout time.Duration) Client {
+	uri, _ := url.Parse(rawURL)
+
+	return NewClient(Config{
+		BaseURL:     uri,
+		APIUsername: "someUser",
+		APIPassword: "somePass",
+		Timeout:     timeout,
+	})
+}
+
+func failureOf(err error) FailureKind {
+	var healthErr *HealthError
+	if errors.As(err, &healthErr) {
+		return healthErr.Kind
+	}
+
+	return ""
+}
+
+func TestPing(t *testing.T) {
+	assertions := assert.New(t)
+
+	status := http.StatusNotFound
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertions.True(strings.HasPrefix(r.URL.Path, "/ordermanagement/v1/orders/health-check-"))
+		if http.StatusGatewayTimeout == status {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	c := healthClient(server.URL, time.Second)
+	assertions.Nil(Ping(context.Background(), c))
+
+	status = http.StatusUnauthorized
+	assertions.Equal(FailureAuth, failureOf(Ping(context.Background(), c)))
+
+	status = http.StatusServiceUnavailable
+	err := Ping(context.Background(), c)
+	assertions.Equal(FailureOutage, failureOf(err))
+	assertions.ErrorIs(err, ServiceUnavailable)
+
+	status = http.StatusGatewayTimeout
+	err = Ping(context.Background(), healthClient(server.URL, 100*time.Millisecond))
+	assertions.Equal(FailureTimeout, failureOf(err))
+}
+
+func TestPing_Connectivity(t *testing.T) {
+	assertions := assert.New(t)
+
+	err := Ping(context.Background(), healthClient("https://klarna.invalid", time.Second))
+	assertions.Equal(FailureDNS, failureOf(err))
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	err = Ping(context.Background(), healthClient(server.URL, time.Second))
+	assertions.Equal(FailureTLS, failureOf(err))
+
+	err = Ping(context.Background(), NewClient(Config{APIUsername: "someUser", Region: "apac"}))
+	assertions.Equal(FailureConfig, failureOf(err))
+}
+
+func TestHealthHandler(t *testing.T) {
+	assertions := assert.New(t)
+
+	status := http.StatusNotFound
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	handler := HealthHandler(healthClient(server.URL, time.Second))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assertions.Equal(http.StatusOK, rec.Code)
+	assertions.JSONEq(`{"status":"ok"}`, rec.Body.String())
+
+	status = http.StatusUnauthorized
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assertions.Equal(http.StatusServiceUnavailable, rec.Code)
+
+	var body map[string]string
+	assertions.Nil(json.Unmarshal(rec.Body.Bytes(), &body))
+	assertions.Equal("unavailable", body["status"])
+	assertions.Equal("auth", body["failure"])
+}
@@ -0,0 +1,118 @@
+package go_klarna
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// maxLoggedBodySize caps the amount of body bytes that are logged, larger bodies are only logged by their size
+const maxLoggedBodySize = 64 << 10
+
+// logExchanges method is the internal middleware logging every request and response with personal data redacted
+func (c *client) logExchanges(next Handler) Handler {
+	return func(op Operation, req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		if !c.config.Logger.Enabled(ctx, c.config.LogLevel) {
+			return next(op, req)
+		}
+
+		body, err := peekRequestBody(req)
+		if nil != err {
+			return nil, err
+		}
+		c.config.Logger.LogAttrs(ctx, c.config.LogLevel, "klarna request",
+			slog.String("operation", op.Name),
+			slog.String("method", req.Method),
+			slog.String("path", req.URL.Path),
+			slog.String("body", redactBody(body)),
+		)
+
+		start := time.Now()
+		res, err := next(op, req)
+		if nil != err {
+			c.config.Logger.LogAttrs(ctx, c.config.LogLevel, "klarna request failed",
+				slog.String("operation", op.Name),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("error", err.Error()),
+			)
+			return nil, err
+		}
+
+		body, err = peekResponseBody(res)
+		if nil != err {
+			return nil, err
+		}
+		c.config.Logger.LogAttrs(ctx, c.config.LogLevel, "klarna response",
+			slog.String("operation", op.Name),
+			slog.Int("status", res.StatusCode),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("correlation_id", res.Header.Get(correlationIDHeader)),
+			slog.String("body", redactBody(body)),
+		)
+
+		return res, nil
+	}
+}
+
+// redactBody function renders a logged body, bodies exceeding the logging limit are only described by their size
+func redactBody(body []byte) string {
+	if len(body) > maxLoggedBodySize {
+		return "[body exceeds the logging limit]"
+	}
+
+	return redactJSON(body)
+}
+
+// peekRequestBody function reads the request body and puts an identical one back in place
+func peekRequestBody(req *http.Request) ([]byte, error) {
+	if nil == req.Body || http.NoBody == req.Body {
+		return nil, nil
+	}
+	if nil != req.GetBody {
+		body, err := req.GetBody()
+		if nil != err {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+
+	data, err := io.ReadAll(req.Body)
+	if nil != err {
+		return nil, err
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+
+	return data, nil
+}
+
+// peekResponseBody function reads up to one byte more than the logging limit from the response body, the body
+// handed over to the caller still yields the full content
+func peekResponseBody(res *http.Response) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxLoggedBodySize+1))
+	if nil != err {
+		res.Body.Close()
+		return nil, err
+	}
+	res.Body = &peekedBody{
+		Reader: io.MultiReader(bytes.NewReader(data), res.Body),
+		closer: res.Body,
+	}
+
+	return data, nil
+}
+
+// peekedBody type is a response body whose beginning was already read
+type peekedBody struct {
+	io.Reader
+	closer io.Closer
+}
+
+// Close method closes the original response body
+func (b *peekedBody) Close() error {
+	return b.closer.Close()
+}
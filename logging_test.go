@@ -0,0 +1,103 @@
+package go_klarna
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestClient_LogsRedactedExchanges(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	order := &OrderManagementOrder{
+		ID: "abc",
+		BillingAddress: &Address{
+			GivenName:     "Jane",
+			FamilyName:    "Doe",
+			Email:         "jane@example.com",
+			Phone:         "+49 30 1234567",
+			StreetAddress: "Main Street 1",
+			City:          "Berlin",
+		},
+		Customer: &OrderManagementCustomer{
+			DateOfBirth:                  "1980-01-01",
+			NationalIdentificationNumber: "19800101-1234",
+		},
+	}
+	setupMux(assertions, "/ordermanagement/v1/orders/abc", nil, http.MethodGet, order)
+	setupMux(assertions, "/credit/v1/sessions", nil, http.MethodPost, &PaymentSession{SessionID: "s1"})
+
+	out := new(bytes.Buffer)
+	uri, _ := url.Parse(testingServer.URL)
+	c := NewClient(Config{
+		BaseURL:  uri,
+		Logger:   slog.New(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		LogLevel: slog.LevelDebug,
+	})
+
+	received, err := NewOrderManagement(c).GetOrder("abc")
+	assertions.Nil(err)
+	assertions.Equal(order, received)
+
+	_, err = NewPaymentSrv(c).CreateNewSession(&PaymentOrder{
+		Customer:        &CustomerInfo{DateOfBirth: "1980-01-01", LastFourSSN: "1234"},
+		ShippingAddress: &Address{GivenName: "Jane", StreetAddress2: "Floor 2"},
+	})
+	assertions.Nil(err)
+
+	logged := out.String()
+	for _, secret := range []string{
+		"Jane", "Doe", "jane@example.com", "1234567", "Main Street", "1980-01-01", "19800101-1234", "Floor 2",
+	} {
+		assertions.NotContains(logged, secret)
+	}
+	assertions.Contains(logged, "Berlin")
+
+	lines := strings.Split(strings.TrimSpace(logged), "\n")
+	assertions.Len(lines, 4)
+	record := make(map[string]interface{})
+	assertions.Nil(json.Unmarshal([]byte(lines[1]), &record))
+	assertions.Equal("klarna response", record["msg"])
+	assertions.Equal("DEBUG", record["level"])
+	assertions.Equal("OrderManagement.GetOrder", record["operation"])
+	assertions.Equal(float64(http.StatusOK), record["status"])
+}
+
+func TestClient_LoggingDisabledBelowLevel(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	setupMux(assertions, "/ordermanagement/v1/orders/abc", nil, http.MethodGet, &OrderManagementOrder{ID: "abc"})
+
+	out := new(bytes.Buffer)
+	uri, _ := url.Parse(testingServer.URL)
+	c := NewClient(Config{
+		BaseURL:  uri,
+		Logger:   slog.New(slog.NewTextHandler(out, nil)),
+		LogLevel: slog.LevelDebug,
+	})
+
+	_, err := NewOrderManagement(c).GetOrder("abc")
+	assertions.Nil(err)
+	assertions.Empty(out.String())
+}
+
+func TestRedactJSON(t *testing.T) {
+	assertions := assert.New(t)
+
+	assertions.Equal(
+		`{"customer":{"date_of_birth":"[REDACTED]"},"lines":[{"email":"[REDACTED]","name":"x"}],"phone":""}`,
+		redactJSON([]byte(`{"customer":{"date_of_birth":"1980-01-01"},"lines":[{"email":"a@b.c","name":"x"}],"phone":""}`)),
+	)
+	assertions.Equal(`{"amount":1000}`, redactJSON([]byte(`{"amount":1000}`)))
+	assertions.Equal("[9 bytes of non JSON content]", redactJSON([]byte("Jane Doe!")))
+	assertions.Equal("", redactJSON(nil))
+}
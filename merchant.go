@@ -0,0 +1,221 @@
+package go_klarna
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrNoMerchant error describes a request that could not be routed to any of the configured merchants
+var ErrNoMerchant = errors.New("no merchant configured for the request")
+
+type (
+	// Merchant type describes one Klarna merchant id (MID) served by a merchant client, every merchant has its own
+	// credentials and may live in another region
+	Merchant struct {
+		// Key identifies the merchant, see WithMerchant
+		Key string
+		// Config of the merchant, it is used to build the client of that merchant
+		Config Config
+		// PurchaseCountries lists the ISO 3166 alpha-2 purchase countries routed to this merchant
+		PurchaseCountries []string
+		// PurchaseCurrencies lists the ISO 4217 purchase currencies routed to this merchant
+		PurchaseCurrencies []string
+	}
+
+	// purchaseInfo type is implemented by the request bodies that carry a purchase country and currency
+	purchaseInfo interface {
+		purchase() (country, currency string)
+	}
+
+	merchantClient struct {
+		merchants  []Merchant
+		clients    map[string]ContextClient
+		defaultKey string
+	}
+)
+
+// WithMerchant function returns a copy of the context routing the requests executed with it to the merchant with
+// the given key, it takes precedence over the purchase country and currency of the request
+func WithMerchant(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, merchantContextKey, key)
+}
+
+// MerchantFromContext function returns the merchant key carried by the context, if there is any
+func MerchantFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(merchantContextKey).(string)
+
+	return key
+}
+
+// Post method executes a Post request on the client of the merchant the body belongs to
+func (mc *merchantClient) Post(path string, body interface{}) (*http.Response, error) {
+	return mc.PostContext(context.Background(), path, body)
+}
+
+// Patch method executes a Patch request on the client of the merchant the body belongs to
+func (mc *merchantClient) Patch(path string, body interface{}) (*http.Response, error) {
+	return mc.PatchContext(context.Background(), path, body)
+}
+
+// Get method executes a Get request on the client of the default merchant, falling back to the other merchants
+func (mc *merchantClient) Get(path string) (*http.Response, error) {
+	return mc.GetContext(context.Background(), path)
+}
+
+// Delete method executes a Delete request on the client of the default merchant, falling back to the other merchants
+func (mc *merchantClient) Delete(path string) (*http.Response, error) {
+	return mc.DeleteContext(context.Background(), path)
+}
+
+// PostContext method is the context aware version of Post
+func (mc *merchantClient) PostContext(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	return mc.dispatch(ctx, body, func(c ContextClient) (*http.Response, error) {
+		return c.PostContext(ctx, path, body)
+	})
+}
+
+// PatchContext method is the context aware version of Patch
+func (mc *merchantClient) PatchContext(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	return mc.dispatch(ctx, body, func(c ContextClient) (*http.Response, error) {
+		return c.PatchContext(ctx, path, body)
+	})
+}
+
+// GetContext method is the context aware version of Get
+func (mc *merchantClient) GetContext(ctx context.Context, path string) (*http.Response, error) {
+	return mc.dispatch(ctx, nil, func(c ContextClient) (*http.Response, error) {
+		return c.GetContext(ctx, path)
+	})
+}
+
+// DeleteContext method is the context aware version of Delete
+func (mc *merchantClient) DeleteContext(ctx context.Context, path string) (*http.Response, error) {
+	return mc.dispatch(ctx, nil, func(c ContextClient) (*http.Response, error) {
+		return c.DeleteContext(ctx, path)
+	})
+}
+
+// dispatch method sends the request to the client of the merchant it belongs to. A request nothing tells the merchant
+// of, e.g. a capture or a GET of an order, is sent to the other merchants in turn as long as the order is not found
+func (mc *merchantClient) dispatch(
+	ctx context.Context,
+	body interface{},
+	send func(c ContextClient) (*http.Response, error),
+) (*http.Response, error) {
+	c, guessed, err := mc.route(ctx, body)
+	if nil != err {
+		return nil, err
+	}
+
+	res, err := send(c)
+	if !guessed || !errors.Is(err, ErrOrderNotFound) {
+		return res, err
+	}
+	for _, m := range mc.merchants {
+		if mc.defaultKey == m.Key {
+			continue
+		}
+		if res, otherErr := send(mc.clients[m.Key]); !errors.Is(otherErr, ErrOrderNotFound) {
+			return res, otherErr
+		}
+	}
+
+	return nil, err
+}
+
+// route method picks the client of the merchant the request belongs to, in order of precedence: the merchant key of
+// the context, the purchase country and currency of the body, the default merchant. guessed tells that the request
+// went to the default merchant for lack of anything else telling its merchant
+func (mc *merchantClient) route(ctx context.Context, body interface{}) (c ContextClient, guessed bool, err error) {
+	if key := MerchantFromContext(ctx); "" != key {
+		c, ok := mc.clients[key]
+		if !ok {
+			return nil, false, fmt.Errorf("%w: unknown merchant %q", ErrNoMerchant, key)
+		}
+		return c, false, nil
+	}
+
+	if p, ok := body.(purchaseInfo); ok {
+		country, currency := p.purchase()
+		for _, m := range mc.merchants {
+			if m.serves(country, currency) {
+				return mc.clients[m.Key], false, nil
+			}
+		}
+		if "" == mc.defaultKey {
+			return nil, false, fmt.Errorf("%w: purchase country %q, currency %q", ErrNoMerchant, country, currency)
+		}
+		return mc.clients[mc.defaultKey], false, nil
+	}
+
+	if "" == mc.defaultKey {
+		return nil, false, fmt.Errorf("%w: no merchant key in the context and no default merchant", ErrNoMerchant)
+	}
+
+	return mc.clients[mc.defaultKey], true, nil
+}
+
+// serves method tells whether a purchase in the given country and currency is routed to the merchant, a merchant
+// without any country or currency is only reachable by its key
+func (m Merchant) serves(country, currency string) bool {
+	if 0 == len(m.PurchaseCountries) && 0 == len(m.PurchaseCurrencies) {
+		return false
+	}
+
+	return containsFold(m.PurchaseCountries, country) && containsFold(m.PurchaseCurrencies, currency)
+}
+
+// containsFold function tells whether the list contains the value ignoring case, an empty list contains everything
+func containsFold(list []string, value string) bool {
+	if 0 == len(list) {
+		return true
+	}
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// purchase method returns the purchase country and currency of the checkout order
+func (o *CheckoutOrder) purchase() (string, string) {
+	return o.PurchaseCountry, o.PurchaseCurrency
+}
+
+// purchase method returns the purchase country and currency of the payment order
+func (o *PaymentOrder) purchase() (string, string) {
+	return o.PurchaseCountry, o.PurchaseCurrency
+}
+
+// NewMerchantClient factory method builds a Client routing every request to the credentials and base URL of the
+// merchant it belongs to, see WithMerchant. Requests that can not be routed otherwise go to the merchant with the
+// defaultKey, an empty defaultKey makes them fail with ErrNoMerchant instead. Requests carrying neither a merchant
+// key nor a purchase country and currency, e.g. GETs, captures, refunds and acknowledges, are sent to the other
+// merchants in turn when the default merchant does not know the order, so the returned Client can be used with any
+// of the services. Pass the merchant key with WithMerchant to spare these extra requests
+func NewMerchantClient(defaultKey string, merchants ...Merchant) (ContextClient, error) {
+	mc := &merchantClient{
+		merchants:  merchants,
+		clients:    make(map[string]ContextClient, len(merchants)),
+		defaultKey: defaultKey,
+	}
+	for _, m := range merchants {
+		if "" == m.Key {
+			return nil, errors.New("merchant key must not be empty")
+		}
+		if _, ok := mc.clients[m.Key]; ok {
+			return nil, fmt.Errorf("duplicate merchant key %q", m.Key)
+		}
+		mc.clients[m.Key] = NewClient(m.Config)
+	}
+	if _, ok := mc.clients[defaultKey]; "" != defaultKey && !ok {
+		return nil, fmt.Errorf("default merchant %q is not configured", defaultKey)
+	}
+
+	return mc, nil
+}
@@ -0,0 +1,142 @@
+package go_klarna
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func merchantServer(received *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		*received = append(*received, user+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"order_id":"abc"}`))
+	}))
+}
+
+func TestMerchantClient_Routing(t *testing.T) {
+	assertions := assert.New(t)
+
+	var received []string
+	eu := merchantServer(&received)
+	defer eu.Close()
+	us := merchantServer(&received)
+	defer us.Close()
+
+	euURL, _ := url.Parse(eu.URL)
+	usURL, _ := url.Parse(us.URL)
+	c, err := NewMerchantClient(
+		"de",
+		Merchant{
+			Key:                "de",
+			Config:             Config{BaseURL: euURL, APIUsername: "K1_de"},
+			PurchaseCountries:  []string{"DE", "AT"},
+			PurchaseCurrencies: []string{"EUR"},
+		},
+		Merchant{
+			Key:               "se",
+			Config:            Config{BaseURL: euURL, APIUsername: "K2_se"},
+			PurchaseCountries: []string{"SE"},
+		},
+		Merchant{
+			Key:                "us",
+			Config:             Config{BaseURL: usURL, APIUsername: "N1_us"},
+			PurchaseCurrencies: []string{"USD"},
+		},
+	)
+	assertions.Nil(err)
+
+	checkout := NewCheckoutSrv(c)
+	assertions.Nil(checkout.CreateNewOrder(&CheckoutOrder{PurchaseCountry: "at", PurchaseCurrency: "EUR"}))
+	assertions.Nil(checkout.CreateNewOrder(&CheckoutOrder{PurchaseCountry: "SE", PurchaseCurrency: "SEK"}))
+	assertions.Nil(checkout.CreateNewOrder(&CheckoutOrder{PurchaseCountry: "US", PurchaseCurrency: "USD"}))
+	assertions.Nil(checkout.CreateNewOrder(&CheckoutOrder{PurchaseCountry: "NL", PurchaseCurrency: "EUR"}))
+
+	om := NewOrderManagement(c)
+	_, err = om.GetOrderContext(WithMerchant(context.Background(), "us"), "abc")
+	assertions.Nil(err)
+	_, err = om.GetOrder("abc")
+	assertions.Nil(err)
+
+	assertions.Equal([]string{
+		"K1_de /checkout/v3/orders",
+		"K2_se /checkout/v3/orders",
+		"N1_us /checkout/v3/orders",
+		"K1_de /checkout/v3/orders",
+		"N1_us /ordermanagement/v1/orders/abc",
+		"K1_de /ordermanagement/v1/orders/abc",
+	}, received)
+}
+
+func TestMerchantClient_FallbackOnUnknownOrder(t *testing.T) {
+	assertions := assert.New(t)
+
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		received = append(received, user+" "+r.Method+" "+r.URL.Path)
+		if "N1_us" != user || "/ordermanagement/v1/orders/us-order/unknown" == r.URL.Path {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"order_id":"us-order"}`))
+	}))
+	defer server.Close()
+
+	uri, _ := url.Parse(server.URL)
+	c, err := NewMerchantClient(
+		"de",
+		Merchant{Key: "de", Config: Config{BaseURL: uri, APIUsername: "K1_de"}},
+		Merchant{Key: "se", Config: Config{BaseURL: uri, APIUsername: "K2_se"}},
+		Merchant{Key: "us", Config: Config{BaseURL: uri, APIUsername: "N1_us"}},
+	)
+	assertions.Nil(err)
+
+	om := NewOrderManagement(c)
+	order, err := om.GetOrder("us-order")
+	assertions.Nil(err)
+	assertions.Equal("us-order", order.ID)
+	assertions.Nil(om.AcknowledgeOrder("us-order"))
+
+	_, err = c.Get("/ordermanagement/v1/orders/us-order/unknown")
+	assertions.ErrorIs(err, ErrOrderNotFound)
+
+	received = nil
+	_, err = om.GetOrderContext(WithMerchant(context.Background(), "se"), "us-order")
+	assertions.ErrorIs(err, ErrOrderNotFound)
+	assertions.Equal([]string{"K2_se GET /ordermanagement/v1/orders/us-order"}, received)
+}
+
+func TestMerchantClient_NoMerchant(t *testing.T) {
+	assertions := assert.New(t)
+
+	c, err := NewMerchantClient("", Merchant{Key: "se", PurchaseCountries: []string{"SE"}})
+	assertions.Nil(err)
+
+	_, err = NewOrderManagement(c).GetOrder("abc")
+	assertions.ErrorIs(err, ErrNoMerchant)
+
+	err = NewCheckoutSrv(c).CreateNewOrder(&CheckoutOrder{PurchaseCountry: "DE"})
+	assertions.ErrorIs(err, ErrNoMerchant)
+
+	_, err = NewOrderManagement(c).GetOrderContext(WithMerchant(context.Background(), "dk"), "abc")
+	assertions.ErrorIs(err, ErrNoMerchant)
+}
+
+func TestNewMerchantClient_InvalidSetup(t *testing.T) {
+	assertions := assert.New(t)
+
+	_, err := NewMerchantClient("de", Merchant{Key: "se"})
+	assertions.EqualError(err, `default merchant "de" is not configured`)
+
+	_, err = NewMerchantClient("", Merchant{Key: "se"}, Merchant{Key: "se"})
+	assertions.EqualError(err, `duplicate merchant key "se"`)
+
+	_, err = NewMerchantClient("", Merchant{})
+	assertions.EqualError(err, "merchant key must not be empty")
+}
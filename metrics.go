@@ -0,0 +1,82 @@
+package go_klarna
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// Endpoint families of the Klarna API
+	CheckoutFamily        EndpointFamily = "checkout"
+	PaymentsFamily        EndpointFamily = "payments"
+	OrderManagementFamily EndpointFamily = "ordermanagement"
+	OtherFamily           EndpointFamily = "other"
+)
+
+type (
+	// EndpointFamily type groups the operations of one Klarna API, e.g. all order management operations
+	EndpointFamily string
+
+	// MetricsRecorder type receives the measurements of every operation executed by the client, see the promklarna
+	// package for a Prometheus implementation
+	MetricsRecorder interface {
+		// InFlight is called with +1 when an operation starts and with -1 when it is done
+		InFlight(op Operation, delta int)
+		// Retried is called every time an operation is retried
+		Retried(op Operation)
+		// Observe records the outcome of an operation, status is 0 when no response was received and errorCode is
+		// the Klarna error code of a failed response, if there is any
+		Observe(op Operation, status int, errorCode string, duration time.Duration)
+	}
+
+	// nopMetrics type is the MetricsRecorder used when none is configured
+	nopMetrics struct{}
+
+	// outcome type sums up how an operation ended
+	outcome struct {
+		status        int
+		correlationID string
+		errorCode     string
+	}
+)
+
+// Family method tells the endpoint family the operation belongs to
+func (op Operation) Family() EndpointFamily {
+	switch {
+	case strings.HasPrefix(op.Route, "/checkout/"):
+		return CheckoutFamily
+	case strings.HasPrefix(op.Route, "/credit/"), strings.HasPrefix(op.Route, "/payments/"):
+		return PaymentsFamily
+	case strings.HasPrefix(op.Route, "/ordermanagement/"):
+		return OrderManagementFamily
+	}
+
+	return OtherFamily
+}
+
+func (nopMetrics) InFlight(Operation, int)                       {}
+func (nopMetrics) Retried(Operation)                             {}
+func (nopMetrics) Observe(Operation, int, string, time.Duration) {}
+
+// outcomeOf function extracts the outcome of an operation from its response or error
+func outcomeOf(res *http.Response, err error) outcome {
+	if nil != res {
+		return outcome{
+			status:        res.StatusCode,
+			correlationID: res.Header.Get(correlationIDHeader),
+		}
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return outcome{
+			status:        apiErr.StatusCode,
+			correlationID: apiErr.CorrelationID,
+			errorCode:     apiErr.ErrorCode,
+		}
+	}
+
+	return outcome{}
+}
@@ -0,0 +1,94 @@
+package go_klarna
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordedMetric struct {
+	family    EndpointFamily
+	operation string
+	status    int
+	errorCode string
+}
+
+type testingMetrics struct {
+	sync.Mutex
+	inFlight int
+	peak     int
+	retries  int
+	observed []recordedMetric
+}
+
+func (m *testingMetrics) InFlight(_ Operation, delta int) {
+	m.Lock()
+	defer m.Unlock()
+	m.inFlight += delta
+	if m.inFlight > m.peak {
+		m.peak = m.inFlight
+	}
+}
+
+func (m *testingMetrics) Retried(Operation) {
+	m.Lock()
+	defer m.Unlock()
+	m.retries++
+}
+
+func (m *testingMetrics) Observe(op Operation, status int, errorCode string, _ time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	m.observed = append(m.observed, recordedMetric{op.Family(), op.Name, status, errorCode})
+}
+
+func TestClient_Metrics(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	attempts := 0
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if 1 == attempts {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"order_id":"abc"}`))
+	})
+	setupErrorMux("/credit/v1/authorizations/token", http.StatusForbidden, `{"error_code":"NOT_ALLOWED"}`)
+
+	metrics := new(testingMetrics)
+	uri, _ := url.Parse(testingServer.URL)
+	c := NewClient(Config{
+		BaseURL: uri,
+		Metrics: metrics,
+		Retry:   &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+	})
+
+	_, err := NewOrderManagement(c).GetOrder("abc")
+	assertions.Nil(err)
+	err = NewPaymentSrv(c).CancelExistingAuthorization("token")
+	assertions.True(IsNotAllowed(err))
+
+	assertions.Equal(0, metrics.inFlight)
+	assertions.Equal(1, metrics.peak)
+	assertions.Equal(1, metrics.retries)
+	assertions.Equal([]recordedMetric{
+		{OrderManagementFamily, "OrderManagement.GetOrder", http.StatusOK, ""},
+		{PaymentsFamily, "Payment.CancelExistingAuthorization", http.StatusForbidden, "NOT_ALLOWED"},
+	}, metrics.observed)
+}
+
+func TestOperation_Family(t *testing.T) {
+	assertions := assert.New(t)
+
+	assertions.Equal(CheckoutFamily, Operation{Route: "/checkout/v3/orders"}.Family())
+	assertions.Equal(PaymentsFamily, Operation{Route: "/credit/v1/sessions"}.Family())
+	assertions.Equal(PaymentsFamily, Operation{Route: "/payments/v1/sessions"}.Family())
+	assertions.Equal(OrderManagementFamily, Operation{Route: "/ordermanagement/v1/orders/{order_id}"}.Family())
+	assertions.Equal(OtherFamily, Operation{Route: "/settlements/v1/payouts"}.Family())
+}
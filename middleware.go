@@ -0,0 +1,64 @@
+package go_klarna
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type (
+	// Operation type describes the Klarna API operation a request belongs to
+	Operation struct {
+		// Name is the qualified name of the operation, e.g. "OrderManagement.CreateCapture"
+		Name string
+		// Route is the templated path of the operation, e.g. "/ordermanagement/v1/orders/{order_id}/captures"
+		Route string
+		// OrderID is the id of the order the operation works on, if there is any
+		OrderID string
+	}
+
+	// Handler type executes a single HTTP exchange with the Klarna API on behalf of the given operation
+	Handler func(op Operation, req *http.Request) (*http.Response, error)
+
+	// Middleware type decorates a Handler, e.g. to inject headers, trace or log requests. Middlewares wrap every
+	// attempt of a request, so a retried request passes through them more than once
+	Middleware func(next Handler) Handler
+)
+
+// OperationFromContext function returns the operation carried by the context, requests issued by the services of
+// this package always carry one
+func OperationFromContext(ctx context.Context) (Operation, bool) {
+	op, ok := ctx.Value(operationContextKey).(Operation)
+
+	return op, ok
+}
+
+// withOperation function returns a copy of the context carrying the given operation
+func withOperation(ctx context.Context, op Operation) context.Context {
+	return context.WithValue(ctx, operationContextKey, op)
+}
+
+// operationFor function resolves the operation of a request, requests not issued by the services of this package
+// are named after their method and endpoint family, e.g. "GET ordermanagement", so order ids never end up in metric
+// labels or span names. Their path is kept as route
+func operationFor(ctx context.Context, method, path string) Operation {
+	if op, ok := OperationFromContext(ctx); ok {
+		return op
+	}
+	if i := strings.IndexByte(path, '?'); 0 <= i {
+		path = path[:i]
+	}
+	op := Operation{Route: path}
+	op.Name = method + " " + string(op.Family())
+
+	return op
+}
+
+// chain function wraps the handler into the given middlewares, the first middleware is the outermost one
+func chain(h Handler, middlewares []Middleware) Handler {
+	for i := len(middlewares) - 1; 0 <= i; i-- {
+		h = middlewares[i](h)
+	}
+
+	return h
+}
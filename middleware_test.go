@@ -0,0 +1,82 @@
+package go_klarna
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestClient_Middleware(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	var receivedHeader string
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc/captures", func(w http.ResponseWriter, r *http.Request) {
+		receivedHeader = r.Header.Get("X-Tenant")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	var calls []string
+	tracing := func(next Handler) Handler {
+		return func(op Operation, req *http.Request) (*http.Response, error) {
+			calls = append(calls, "outer:"+op.Name)
+			return next(op, req)
+		}
+	}
+	tenant := func(next Handler) Handler {
+		return func(op Operation, req *http.Request) (*http.Response, error) {
+			calls = append(calls, "inner:"+op.Route)
+			req.Header.Set("X-Tenant", "de")
+			return next(op, req)
+		}
+	}
+
+	uri, _ := url.Parse(testingServer.URL)
+	c := NewClient(Config{
+		BaseURL:    uri,
+		HTTPClient: testingServer.Client(),
+		Middleware: []Middleware{tracing, tenant},
+	})
+	err := NewOrderManagement(c).CreateCapture("abc", &CreateCapture{})
+
+	assertions.Nil(err)
+	assertions.Equal("de", receivedHeader)
+	assertions.Equal([]string{
+		"outer:OrderManagement.CreateCapture",
+		"inner:/ordermanagement/v1/orders/{order_id}/captures",
+	}, calls)
+}
+
+func TestClient_MiddlewareRawRequest(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	setupMux(assertions, "/custom", nil, http.MethodGet, nil)
+
+	var seen Operation
+	uri, _ := url.Parse(testingServer.URL)
+	c := NewClient(Config{
+		BaseURL: uri,
+		Middleware: []Middleware{func(next Handler) Handler {
+			return func(op Operation, req *http.Request) (*http.Response, error) {
+				seen = op
+				return next(op, req)
+			}
+		}},
+	})
+	_, err := c.Get("/custom?page=2")
+
+	assertions.Nil(err)
+	assertions.Equal(Operation{Name: "GET other", Route: "/custom"}, seen)
+
+	ctx := context.Background()
+	assertions.Equal(
+		operationFor(ctx, http.MethodGet, "/ordermanagement/v1/orders/o-1").Name,
+		operationFor(ctx, http.MethodGet, "/ordermanagement/v1/orders/o-2").Name,
+	)
+	assertions.Equal("GET ordermanagement", operationFor(ctx, http.MethodGet, "/ordermanagement/v1/orders/o-1").Name)
+}
@@ -1,8 +1,12 @@
 package go_klarna
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
 )
 
 const (
@@ -18,8 +22,14 @@ const (
 	Accepted = "ACCEPTED"
 	Pending  = "PENDING"
 	Rejected = "REJECTED"
+
+	captureIDHeader = "Capture-Id"
+	refundIDHeader  = "Refund-Id"
 )
 
+// ErrMissingResourceID error describes a created capture or refund whose id Klarna did not return
+var ErrMissingResourceID = errors.New("klarna did not return the id of the created resource")
+
 type (
 	OrderManagementSrv interface {
 		// Order Management - order end-points
@@ -43,6 +53,39 @@ type (
 		CreateCapture(string, *CreateCapture) error
 	}
 
+	// OrderManagementContextSrv type is the OrderManagementSrv with context aware variants of its methods, and the
+	// captures and refunds returning the resource created by Klarna
+	OrderManagementContextSrv interface {
+		OrderManagementSrv
+
+		GetOrderContext(context.Context, string) (*OrderManagementOrder, error)
+		AcknowledgeOrderContext(context.Context, string) error
+		SetOrderAmountLinesContext(context.Context, string, *OrderAmountLines) error
+		AdjustOrderAmountLinesContext(context.Context, string, *AdjustAmountLines) error
+		CancelOrderContext(context.Context, string) error
+		UpdateCustomerAddressContext(context.Context, string, *CustomerAddress) error
+		ExtendAuthorizationTimeContext(context.Context, string) error
+		UpdateMerchantReferencesContext(context.Context, string, *MerchantReferences) error
+		ReleaseRemainingAuthorizationContext(context.Context, string) error
+		GetRefundContext(context.Context, string, string) error
+		CreateRefundContext(context.Context, string, *OrderManagementRefund) error
+		GetAllCapturesContext(context.Context, string) ([]*Capture, error)
+		TriggerResendCustomerCommunicationContext(context.Context, string, string) error
+		AddCaptureShippingInfoContext(context.Context, string, string, []*OrderManagementShippingInfo) error
+		GetCaptureContext(context.Context, string, string) (*Capture, error)
+		CreateCaptureContext(context.Context, string, *CreateCapture) error
+
+		// Variants returning the capture or refund created by Klarna
+		CaptureOrder(string, *CreateCapture) (string, error)
+		CaptureOrderAndGet(string, *CreateCapture) (*Capture, error)
+		RefundOrder(string, *OrderManagementRefund) (string, error)
+		RefundOrderAndGet(string, *OrderManagementRefund) (*OrderManagementRefund, error)
+		CaptureOrderContext(context.Context, string, *CreateCapture) (string, error)
+		CaptureOrderAndGetContext(context.Context, string, *CreateCapture) (*Capture, error)
+		RefundOrderContext(context.Context, string, *OrderManagementRefund) (string, error)
+		RefundOrderAndGetContext(context.Context, string, *OrderManagementRefund) (*OrderManagementRefund, error)
+	}
+
 	orderManagementSrv struct {
 		client Client
 	}
@@ -102,10 +145,12 @@ type (
 	}
 
 	OrderManagementRefund struct {
-		RefundAmount int     `json:"refund_amount,omitempty"`
-		RefundedAt   string  `json:"refunded_at,omitempty"` // DateTime string of ISO 8601
-		Description  string  `json:"description,omitempty"`
-		OrderLines   []*Line `json:"order_lines,omitempty"`
+		ID             string  `json:"refund_id,omitempty"`
+		RefundAmount   int     `json:"refund_amount,omitempty"`
+		RefundedAmount int     `json:"refunded_amount,omitempty"`
+		RefundedAt     string  `json:"refunded_at,omitempty"` // DateTime string of ISO 8601
+		Description    string  `json:"description,omitempty"`
+		OrderLines     []*Line `json:"order_lines,omitempty"`
 	}
 
 	OrderAmountLines struct {
@@ -140,135 +185,392 @@ type (
 )
 
 func (srv *orderManagementSrv) GetRefund(oid, rid string) error {
-	path := fmt.Sprintf("%s/%s/refunds/%s", OrderManagementEndpoint, oid, rid)
-	_, err := srv.client.Get(path)
+	return srv.GetRefundContext(context.Background(), oid, rid)
+}
+
+func (srv *orderManagementSrv) GetRefundContext(ctx context.Context, oid, rid string) error {
+	_, err := send(ctx, srv.client, request{
+		op:     "OrderManagement.GetRefund",
+		method: http.MethodGet,
+		route:  OrderManagementEndpoint + "/{order_id}/refunds/{refund_id}",
+		params: []string{oid, rid},
+	})
 
 	return err
 }
 
 func (srv *orderManagementSrv) CreateRefund(oid string, rf *OrderManagementRefund) error {
-	path := fmt.Sprintf("%s/%s/refunds", OrderManagementEndpoint, oid)
-	_, err := srv.client.Post(path, rf)
+	return srv.CreateRefundContext(context.Background(), oid, rf)
+}
+
+func (srv *orderManagementSrv) CreateRefundContext(ctx context.Context, oid string, rf *OrderManagementRefund) error {
+	_, err := srv.RefundOrderContext(ctx, oid, rf)
+	if errors.Is(err, ErrMissingResourceID) {
+		return nil
+	}
 
 	return err
 }
 
 func (srv *orderManagementSrv) TriggerResendCustomerCommunication(oid, cid string) error {
-	path := fmt.Sprintf("%s/%s/captures/%s/trigger-send-out", OrderManagementEndpoint, oid, cid)
-	_, err := srv.client.Post(path, nil)
+	return srv.TriggerResendCustomerCommunicationContext(context.Background(), oid, cid)
+}
+
+func (srv *orderManagementSrv) TriggerResendCustomerCommunicationContext(ctx context.Context, oid, cid string) error {
+	_, err := send(ctx, srv.client, request{
+		op:         "OrderManagement.TriggerResendCustomerCommunication",
+		method:     http.MethodPost,
+		route:      OrderManagementEndpoint + "/{order_id}/captures/{capture_id}/trigger-send-out",
+		params:     []string{oid, cid},
+		idempotent: true,
+	})
 
 	return err
 }
 
 func (srv *orderManagementSrv) AddCaptureShippingInfo(oid, cid string, si []*OrderManagementShippingInfo) error {
-	path := fmt.Sprintf("%s/%s/captures/%s/shipping-info", OrderManagementEndpoint, oid, cid)
-	_, err := srv.client.Post(path, si)
+	return srv.AddCaptureShippingInfoContext(context.Background(), oid, cid, si)
+}
+
+func (srv *orderManagementSrv) AddCaptureShippingInfoContext(
+	ctx context.Context,
+	oid, cid string,
+	si []*OrderManagementShippingInfo,
+) error {
+	_, err := send(ctx, srv.client, request{
+		op:         "OrderManagement.AddCaptureShippingInfo",
+		method:     http.MethodPost,
+		route:      OrderManagementEndpoint + "/{order_id}/captures/{capture_id}/shipping-info",
+		params:     []string{oid, cid},
+		body:       si,
+		idempotent: true,
+	})
 
 	return err
 }
 
 func (srv *orderManagementSrv) GetCapture(oid, cid string) (*Capture, error) {
-	path := fmt.Sprintf("%s/%s/captures/%s", OrderManagementEndpoint, oid, cid)
-	res, err := srv.client.Get(path)
-	if nil != err {
-		return nil, err
-	}
+	return srv.GetCaptureContext(context.Background(), oid, cid)
+}
 
-	var capture *Capture
-	err = json.NewDecoder(res.Body).Decode(&capture)
+func (srv *orderManagementSrv) GetCaptureContext(ctx context.Context, oid, cid string) (*Capture, error) {
+	capture, _, err := fetch[Capture](ctx, srv.client, request{
+		op:     "OrderManagement.GetCapture",
+		method: http.MethodGet,
+		route:  OrderManagementEndpoint + "/{order_id}/captures/{capture_id}",
+		params: []string{oid, cid},
+	})
 
 	return capture, err
 }
 
 func (srv *orderManagementSrv) CreateCapture(oid string, c *CreateCapture) error {
-	path := fmt.Sprintf("%s/%s/captures", OrderManagementEndpoint, oid)
-	_, err := srv.client.Post(path, c)
+	return srv.CreateCaptureContext(context.Background(), oid, c)
+}
+
+func (srv *orderManagementSrv) CreateCaptureContext(ctx context.Context, oid string, c *CreateCapture) error {
+	_, err := srv.CaptureOrderContext(ctx, oid, c)
+	if errors.Is(err, ErrMissingResourceID) {
+		return nil
+	}
 
 	return err
 }
 
 func (srv *orderManagementSrv) GetAllCaptures(oid string) ([]*Capture, error) {
-	path := fmt.Sprintf("%s/%s/captures", OrderManagementEndpoint, oid)
-	res, err := srv.client.Get(path)
+	return srv.GetAllCapturesContext(context.Background(), oid)
+}
+
+func (srv *orderManagementSrv) GetAllCapturesContext(ctx context.Context, oid string) ([]*Capture, error) {
+	captures, _, err := fetch[[]*Capture](ctx, srv.client, request{
+		op:     "OrderManagement.GetAllCaptures",
+		method: http.MethodGet,
+		route:  OrderManagementEndpoint + "/{order_id}/captures",
+		params: []string{oid},
+	})
 	if nil != err {
 		return nil, err
 	}
-	var captures []*Capture
-	err = json.NewDecoder(res.Body).Decode(&captures)
 
-	return captures, err
+	return *captures, nil
 }
 
 func (srv *orderManagementSrv) GetOrder(id string) (*OrderManagementOrder, error) {
-	path := OrderManagementEndpoint + "/" + id
-
-	res, err := srv.client.Get(path)
-	if nil != err {
-		return nil, err
-	}
+	return srv.GetOrderContext(context.Background(), id)
+}
 
-	o := new(OrderManagementOrder)
-	err = json.NewDecoder(res.Body).Decode(o)
+func (srv *orderManagementSrv) GetOrderContext(ctx context.Context, id string) (*OrderManagementOrder, error) {
+	o, _, err := fetch[OrderManagementOrder](ctx, srv.client, request{
+		op:     "OrderManagement.GetOrder",
+		method: http.MethodGet,
+		route:  OrderManagementEndpoint + "/{order_id}",
+		params: []string{id},
+	})
 
 	return o, err
 }
 
 func (srv *orderManagementSrv) AcknowledgeOrder(oid string) error {
-	path := fmt.Sprintf("%s/%s/acknowledge", OrderManagementEndpoint, oid)
-	_, err := srv.client.Post(path, nil)
+	return srv.AcknowledgeOrderContext(context.Background(), oid)
+}
+
+func (srv *orderManagementSrv) AcknowledgeOrderContext(ctx context.Context, oid string) error {
+	_, err := send(ctx, srv.client, request{
+		op:         "OrderManagement.AcknowledgeOrder",
+		method:     http.MethodPost,
+		route:      OrderManagementEndpoint + "/{order_id}/acknowledge",
+		params:     []string{oid},
+		idempotent: true,
+	})
 
 	return err
 }
 
 func (srv *orderManagementSrv) SetOrderAmountLines(oid string, oal *OrderAmountLines) error {
-	path := fmt.Sprintf("%s/%s/authorization", OrderManagementEndpoint, oid)
-	_, err := srv.client.Patch(path, oal)
+	return srv.SetOrderAmountLinesContext(context.Background(), oid, oal)
+}
+
+func (srv *orderManagementSrv) SetOrderAmountLinesContext(
+	ctx context.Context,
+	oid string,
+	oal *OrderAmountLines,
+) error {
+	_, err := send(ctx, srv.client, request{
+		op:     "OrderManagement.SetOrderAmountLines",
+		method: http.MethodPatch,
+		route:  OrderManagementEndpoint + "/{order_id}/authorization",
+		params: []string{oid},
+		body:   oal,
+	})
 
 	return err
 }
 
 func (srv *orderManagementSrv) AdjustOrderAmountLines(oid string, adjust *AdjustAmountLines) error {
-	path := fmt.Sprintf("%s/%s/authorization-adjustments", OrderManagementEndpoint, oid)
-	_, err := srv.client.Post(path, adjust)
+	return srv.AdjustOrderAmountLinesContext(context.Background(), oid, adjust)
+}
+
+func (srv *orderManagementSrv) AdjustOrderAmountLinesContext(
+	ctx context.Context,
+	oid string,
+	adjust *AdjustAmountLines,
+) error {
+	_, err := send(ctx, srv.client, request{
+		op:         "OrderManagement.AdjustOrderAmountLines",
+		method:     http.MethodPost,
+		route:      OrderManagementEndpoint + "/{order_id}/authorization-adjustments",
+		params:     []string{oid},
+		body:       adjust,
+		idempotent: true,
+	})
 
 	return err
 }
 
 func (srv *orderManagementSrv) CancelOrder(oid string) error {
-	path := fmt.Sprintf("%s/%s/cancel", OrderManagementEndpoint, oid)
-	_, err := srv.client.Post(path, nil)
+	return srv.CancelOrderContext(context.Background(), oid)
+}
+
+func (srv *orderManagementSrv) CancelOrderContext(ctx context.Context, oid string) error {
+	_, err := send(ctx, srv.client, request{
+		op:         "OrderManagement.CancelOrder",
+		method:     http.MethodPost,
+		route:      OrderManagementEndpoint + "/{order_id}/cancel",
+		params:     []string{oid},
+		idempotent: true,
+	})
 
 	return err
 }
 
 func (srv *orderManagementSrv) UpdateCustomerAddress(oid string, ca *CustomerAddress) error {
-	path := fmt.Sprintf("%s/%s/customer-details", OrderManagementEndpoint, oid)
-	_, err := srv.client.Post(path, ca)
+	return srv.UpdateCustomerAddressContext(context.Background(), oid, ca)
+}
+
+func (srv *orderManagementSrv) UpdateCustomerAddressContext(
+	ctx context.Context,
+	oid string,
+	ca *CustomerAddress,
+) error {
+	_, err := send(ctx, srv.client, request{
+		op:         "OrderManagement.UpdateCustomerAddress",
+		method:     http.MethodPost,
+		route:      OrderManagementEndpoint + "/{order_id}/customer-details",
+		params:     []string{oid},
+		body:       ca,
+		idempotent: true,
+	})
 
 	return err
 }
 
 func (srv *orderManagementSrv) ExtendAuthorizationTime(oid string) error {
-	path := fmt.Sprintf("%s/%s/extend-authorization-time", OrderManagementEndpoint, oid)
-	_, err := srv.client.Post(path, nil)
+	return srv.ExtendAuthorizationTimeContext(context.Background(), oid)
+}
+
+func (srv *orderManagementSrv) ExtendAuthorizationTimeContext(ctx context.Context, oid string) error {
+	_, err := send(ctx, srv.client, request{
+		op:         "OrderManagement.ExtendAuthorizationTime",
+		method:     http.MethodPost,
+		route:      OrderManagementEndpoint + "/{order_id}/extend-authorization-time",
+		params:     []string{oid},
+		idempotent: true,
+	})
 
 	return err
 }
 
 func (srv *orderManagementSrv) UpdateMerchantReferences(oid string, mr *MerchantReferences) error {
-	path := fmt.Sprintf("%s/%s/merchant-references", OrderManagementEndpoint, oid)
-	_, err := srv.client.Post(path, mr)
+	return srv.UpdateMerchantReferencesContext(context.Background(), oid, mr)
+}
+
+func (srv *orderManagementSrv) UpdateMerchantReferencesContext(
+	ctx context.Context,
+	oid string,
+	mr *MerchantReferences,
+) error {
+	_, err := send(ctx, srv.client, request{
+		op:         "OrderManagement.UpdateMerchantReferences",
+		method:     http.MethodPost,
+		route:      OrderManagementEndpoint + "/{order_id}/merchant-references",
+		params:     []string{oid},
+		body:       mr,
+		idempotent: true,
+	})
 
 	return err
 }
 
 func (srv *orderManagementSrv) ReleaseRemainingAuthorization(oid string) error {
-	path := fmt.Sprintf("%s/%s/release-remaining-authorization", OrderManagementEndpoint, oid)
-	_, err := srv.client.Post(path, nil)
+	return srv.ReleaseRemainingAuthorizationContext(context.Background(), oid)
+}
+
+func (srv *orderManagementSrv) ReleaseRemainingAuthorizationContext(ctx context.Context, oid string) error {
+	_, err := send(ctx, srv.client, request{
+		op:         "OrderManagement.ReleaseRemainingAuthorization",
+		method:     http.MethodPost,
+		route:      OrderManagementEndpoint + "/{order_id}/release-remaining-authorization",
+		params:     []string{oid},
+		idempotent: true,
+	})
 
 	return err
 }
 
-func NewOrderManagement(c Client) OrderManagementSrv {
+// CaptureOrder method creates a capture like CreateCapture and returns the id Klarna assigned to it
+func (srv *orderManagementSrv) CaptureOrder(oid string, c *CreateCapture) (string, error) {
+	return srv.CaptureOrderContext(context.Background(), oid, c)
+}
+
+// CaptureOrderContext method is the context aware variant of CaptureOrder
+func (srv *orderManagementSrv) CaptureOrderContext(ctx context.Context, oid string, c *CreateCapture) (string, error) {
+	res, err := send(ctx, srv.client, request{
+		op:         "OrderManagement.CreateCapture",
+		method:     http.MethodPost,
+		route:      OrderManagementEndpoint + "/{order_id}/captures",
+		params:     []string{oid},
+		body:       c,
+		idempotent: true,
+	})
+	if nil != err {
+		return "", err
+	}
+
+	return createdID(res, captureIDHeader)
+}
+
+// CaptureOrderAndGet method creates a capture like CaptureOrder and fetches it by the id Klarna assigned to it
+func (srv *orderManagementSrv) CaptureOrderAndGet(oid string, c *CreateCapture) (*Capture, error) {
+	return srv.CaptureOrderAndGetContext(context.Background(), oid, c)
+}
+
+// CaptureOrderAndGetContext method is the context aware variant of CaptureOrderAndGet
+func (srv *orderManagementSrv) CaptureOrderAndGetContext(
+	ctx context.Context,
+	oid string,
+	c *CreateCapture,
+) (*Capture, error) {
+	cid, err := srv.CaptureOrderContext(ctx, oid, c)
+	if nil != err {
+		return nil, err
+	}
+
+	return srv.GetCaptureContext(ctx, oid, cid)
+}
+
+// RefundOrder method creates a refund like CreateRefund and returns the id Klarna assigned to it
+func (srv *orderManagementSrv) RefundOrder(oid string, rf *OrderManagementRefund) (string, error) {
+	return srv.RefundOrderContext(context.Background(), oid, rf)
+}
+
+// RefundOrderContext method is the context aware variant of RefundOrder
+func (srv *orderManagementSrv) RefundOrderContext(
+	ctx context.Context,
+	oid string,
+	rf *OrderManagementRefund,
+) (string, error) {
+	res, err := send(ctx, srv.client, request{
+		op:         "OrderManagement.CreateRefund",
+		method:     http.MethodPost,
+		route:      OrderManagementEndpoint + "/{order_id}/refunds",
+		params:     []string{oid},
+		body:       rf,
+		idempotent: true,
+	})
+	if nil != err {
+		return "", err
+	}
+
+	return createdID(res, refundIDHeader)
+}
+
+// RefundOrderAndGet method creates a refund like RefundOrder and fetches it by the id Klarna assigned to it
+func (srv *orderManagementSrv) RefundOrderAndGet(
+	oid string,
+	rf *OrderManagementRefund,
+) (*OrderManagementRefund, error) {
+	return srv.RefundOrderAndGetContext(context.Background(), oid, rf)
+}
+
+// RefundOrderAndGetContext method is the context aware variant of RefundOrderAndGet
+func (srv *orderManagementSrv) RefundOrderAndGetContext(
+	ctx context.Context,
+	oid string,
+	rf *OrderManagementRefund,
+) (*OrderManagementRefund, error) {
+	rid, err := srv.RefundOrderContext(ctx, oid, rf)
+	if nil != err {
+		return nil, err
+	}
+
+	refund, _, err := fetch[OrderManagementRefund](ctx, srv.client, request{
+		op:     "OrderManagement.GetRefund",
+		method: http.MethodGet,
+		route:  OrderManagementEndpoint + "/{order_id}/refunds/{refund_id}",
+		params: []string{oid, rid},
+	})
+
+	return refund, err
+}
+
+// createdID function returns the id of a created resource, taken from the given header or else from the last
+// segment of the Location header
+func createdID(res *result, header string) (string, error) {
+	if id := res.Header.Get(header); "" != id {
+		return id, nil
+	}
+
+	if location := res.Header.Get("Location"); "" != location {
+		if u, err := url.Parse(location); nil == err {
+			if id := path.Base(strings.TrimRight(u.Path, "/")); "" != id && "." != id && "/" != id {
+				return url.PathUnescape(id)
+			}
+		}
+	}
+
+	return "", ErrMissingResourceID
+}
+
+func NewOrderManagement(c Client) OrderManagementContextSrv {
 	return &orderManagementSrv{c}
 }
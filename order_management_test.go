@@ -391,3 +391,68 @@ func TestOrderManagementSrv_ReleaseRemainingAuthorization(t *testing.T) {
 
 	assertions.Empty(err)
 }
+
+func TestOrderManagementSrv_CaptureOrder(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc/captures", func(w http.ResponseWriter, r *http.Request) {
+		assertions.Equal(http.MethodPost, r.Method)
+		w.Header().Set("Location", testingServer.URL+"/ordermanagement/v1/orders/abc/captures/cap-1")
+		w.Header().Set("Capture-Id", "cap-1")
+		w.WriteHeader(http.StatusCreated)
+	})
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc/captures/cap-1", func(w http.ResponseWriter, r *http.Request) {
+		assertions.Equal(http.MethodGet, r.Method)
+		w.Write([]byte(`{"capture_id":"cap-1","capture_amount":100}`))
+	})
+
+	srv := NewOrderManagement(testingClient())
+	cid, err := srv.CaptureOrder("abc", &CreateCapture{CapturedAmount: 100})
+	assertions.Nil(err)
+	assertions.Equal("cap-1", cid)
+
+	capture, err := srv.CaptureOrderAndGet("abc", &CreateCapture{CapturedAmount: 100})
+	assertions.Nil(err)
+	assertions.Equal("cap-1", capture.ID)
+	assertions.Equal(100, capture.CaptureAmount)
+}
+
+func TestOrderManagementSrv_RefundOrder(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc/refunds", func(w http.ResponseWriter, r *http.Request) {
+		assertions.Equal(http.MethodPost, r.Method)
+		w.Header().Set("Location", testingServer.URL+"/ordermanagement/v1/orders/abc/refunds/ref-1")
+		w.WriteHeader(http.StatusCreated)
+	})
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc/refunds/ref-1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"refund_id":"ref-1","refunded_amount":50}`))
+	})
+
+	srv := NewOrderManagement(testingClient())
+	rid, err := srv.RefundOrder("abc", &OrderManagementRefund{RefundAmount: 50})
+	assertions.Nil(err)
+	assertions.Equal("ref-1", rid)
+
+	refund, err := srv.RefundOrderAndGet("abc", &OrderManagementRefund{RefundAmount: 50})
+	assertions.Nil(err)
+	assertions.Equal("ref-1", refund.ID)
+	assertions.Equal(50, refund.RefundedAmount)
+}
+
+func TestOrderManagementSrv_RefundOrderWithoutID(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc/refunds", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	_, err := NewOrderManagement(testingClient()).RefundOrder("abc", &OrderManagementRefund{})
+	assertions.ErrorIs(err, ErrMissingResourceID)
+}
@@ -1,8 +1,8 @@
 package go_klarna
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"net/http"
 )
 
 const (
@@ -19,6 +19,16 @@ type (
 		CancelExistingAuthorization(string) error
 	}
 
+	// PaymentContextSrv type is the PaymentSrv with context aware variants of its methods
+	PaymentContextSrv interface {
+		PaymentSrv
+
+		CreateNewSessionContext(context.Context, *PaymentOrder) (*PaymentSession, error)
+		UpdateExistingSessionContext(context.Context, string, *PaymentOrder) error
+		CreateNewOrderContext(context.Context, string, *PaymentOrder) (*PaymentOrderInfo, error)
+		CancelExistingAuthorizationContext(context.Context, string) error
+	}
+
 	paymentSrv struct {
 		client Client
 	}
@@ -91,47 +101,79 @@ type (
 // CreateNewSession method calls payment session api and return an error if there is any, PaymentSession struct
 // is returned on success
 func (srv *paymentSrv) CreateNewSession(po *PaymentOrder) (*PaymentSession, error) {
-	res, err := srv.client.Post(paymentSessionApiURL, po)
-	if nil != err {
-		return nil, err
-	}
-	ps := new(PaymentSession)
-	err = json.NewDecoder(res.Body).Decode(ps)
+	return srv.CreateNewSessionContext(context.Background(), po)
+}
+
+// CreateNewSessionContext method is the context aware version of CreateNewSession
+func (srv *paymentSrv) CreateNewSessionContext(ctx context.Context, po *PaymentOrder) (*PaymentSession, error) {
+	ps, _, err := fetch[PaymentSession](ctx, srv.client, request{
+		op:     "Payment.CreateNewSession",
+		method: http.MethodPost,
+		route:  paymentSessionApiURL,
+		body:   po,
+	})
 
 	return ps, err
 }
 
 // UpdateExistingSession method calls update payment session api and return an error if there is any
 func (srv *paymentSrv) UpdateExistingSession(id string, po *PaymentOrder) error {
-	uri := fmt.Sprintf("%s/%s", paymentSessionApiURL, id)
-	_, err := srv.client.Post(uri, po)
+	return srv.UpdateExistingSessionContext(context.Background(), id, po)
+}
+
+// UpdateExistingSessionContext method is the context aware version of UpdateExistingSession
+func (srv *paymentSrv) UpdateExistingSessionContext(ctx context.Context, id string, po *PaymentOrder) error {
+	_, err := send(ctx, srv.client, request{
+		op:     "Payment.UpdateExistingSession",
+		method: http.MethodPost,
+		route:  paymentSessionApiURL + "/{session_id}",
+		params: []string{id},
+		body:   po,
+	})
 
 	return err
 }
 
 // CreateNewOrder method creates a new payment order with the given token and order
 func (srv *paymentSrv) CreateNewOrder(token string, po *PaymentOrder) (*PaymentOrderInfo, error) {
-	path := fmt.Sprintf("%s/%s/order", paymentOrdersApiURL, token)
-	res, err := srv.client.Post(path, po)
-	if nil != err {
-		return nil, err
-	}
+	return srv.CreateNewOrderContext(context.Background(), token, po)
+}
 
-	pof := new(PaymentOrderInfo)
-	err = json.NewDecoder(res.Body).Decode(pof)
+// CreateNewOrderContext method is the context aware version of CreateNewOrder
+func (srv *paymentSrv) CreateNewOrderContext(
+	ctx context.Context,
+	token string,
+	po *PaymentOrder,
+) (*PaymentOrderInfo, error) {
+	pof, _, err := fetch[PaymentOrderInfo](ctx, srv.client, request{
+		op:     "Payment.CreateNewOrder",
+		method: http.MethodPost,
+		route:  paymentOrdersApiURL + "/{authorization_token}/order",
+		params: []string{token},
+		body:   po,
+	})
 
 	return pof, err
 }
 
 // CancelExistingAuthorization method calls the API end-point
 func (srv *paymentSrv) CancelExistingAuthorization(token string) error {
-	path := fmt.Sprintf("%s/%s", paymentOrdersApiURL, token)
-	_, err := srv.client.Delete(path)
+	return srv.CancelExistingAuthorizationContext(context.Background(), token)
+}
+
+// CancelExistingAuthorizationContext method is the context aware version of CancelExistingAuthorization
+func (srv *paymentSrv) CancelExistingAuthorizationContext(ctx context.Context, token string) error {
+	_, err := send(ctx, srv.client, request{
+		op:     "Payment.CancelExistingAuthorization",
+		method: http.MethodDelete,
+		route:  paymentOrdersApiURL + "/{authorization_token}",
+		params: []string{token},
+	})
 
 	return err
 }
 
 // NewPaymentSrv Return a new payment instance while providing
-func NewPaymentSrv(c Client) PaymentSrv {
+func NewPaymentSrv(c Client) PaymentContextSrv {
 	return &paymentSrv{c}
 }
@@ -0,0 +1,77 @@
+// Package promklarna implements the go_klarna MetricsRecorder on top of Prometheus
+package promklarna
+
+import (
+	klarna "github.com/Flaconi/go-klarna"
+	"github.com/prometheus/client_golang/prometheus"
+	"strconv"
+	"time"
+)
+
+const namespace = "klarna"
+
+// Recorder type is a klarna.MetricsRecorder exposing the measurements as Prometheus metrics, all of them are
+// labelled by endpoint family and operation
+type Recorder struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+	retries  *prometheus.CounterVec
+}
+
+// InFlight method tracks the operations currently in progress
+func (r *Recorder) InFlight(op klarna.Operation, delta int) {
+	r.inFlight.WithLabelValues(string(op.Family()), op.Name).Add(float64(delta))
+}
+
+// Retried method counts the retries of an operation
+func (r *Recorder) Retried(op klarna.Operation) {
+	r.retries.WithLabelValues(string(op.Family()), op.Name).Inc()
+}
+
+// Observe method counts the operation by status and Klarna error code and records its latency
+func (r *Recorder) Observe(op klarna.Operation, status int, errorCode string, duration time.Duration) {
+	family := string(op.Family())
+	statusLabel := "none"
+	if 0 != status {
+		statusLabel = strconv.Itoa(status)
+	}
+
+	r.requests.WithLabelValues(family, op.Name, statusLabel, errorCode).Inc()
+	r.duration.WithLabelValues(family, op.Name, statusLabel).Observe(duration.Seconds())
+}
+
+// NewRecorder factory method creates the metrics and registers them with the given registerer
+func NewRecorder(reg prometheus.Registerer) (*Recorder, error) {
+	r := &Recorder{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Klarna API operations by endpoint family, operation, HTTP status and Klarna error code.",
+		}, []string{"family", "operation", "status", "error_code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of Klarna API operations including retries.",
+			Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"family", "operation", "status"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "requests_in_flight",
+			Help:      "Klarna API operations currently in progress.",
+		}, []string{"family", "operation"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "request_retries_total",
+			Help:      "Retried attempts of Klarna API operations.",
+		}, []string{"family", "operation"}),
+	}
+
+	for _, c := range []prometheus.Collector{r.requests, r.duration, r.inFlight, r.retries} {
+		if err := reg.Register(c); nil != err {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
@@ -0,0 +1,41 @@
+package promklarna
+
+import (
+	klarna "github.com/Flaconi/go-klarna"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	assertions := assert.New(t)
+
+	reg := prometheus.NewRegistry()
+	r, err := NewRecorder(reg)
+	assertions.Nil(err)
+
+	capture := klarna.Operation{
+		Name:  "OrderManagement.CreateCapture",
+		Route: "/ordermanagement/v1/orders/{order_id}/captures",
+	}
+	r.InFlight(capture, 1)
+	assertions.Equal(1.0, testutil.ToFloat64(r.inFlight.WithLabelValues("ordermanagement", capture.Name)))
+
+	r.Retried(capture)
+	r.Observe(capture, 403, "CAPTURE_NOT_ALLOWED", 120*time.Millisecond)
+	r.Observe(capture, 0, "", time.Second)
+	r.InFlight(capture, -1)
+
+	assertions.Equal(0.0, testutil.ToFloat64(r.inFlight.WithLabelValues("ordermanagement", capture.Name)))
+	assertions.Equal(1.0, testutil.ToFloat64(r.retries.WithLabelValues("ordermanagement", capture.Name)))
+	assertions.Equal(1.0, testutil.ToFloat64(
+		r.requests.WithLabelValues("ordermanagement", capture.Name, "403", "CAPTURE_NOT_ALLOWED"),
+	))
+	assertions.Equal(1.0, testutil.ToFloat64(r.requests.WithLabelValues("ordermanagement", capture.Name, "none", "")))
+	assertions.Equal(2, testutil.CollectAndCount(r.duration))
+
+	_, err = NewRecorder(reg)
+	assertions.Error(err)
+}
@@ -0,0 +1,135 @@
+package go_klarna
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultThrottleBackoff is how long the client backs off after a 429 response which has no Retry-After header
+const defaultThrottleBackoff = time.Second
+
+type (
+	// RateLimit type caps the requests the client sends to one endpoint family
+	RateLimit struct {
+		// RequestsPerSecond is the sustained rate of the token bucket, zero means unlimited
+		RequestsPerSecond float64
+		// Burst is the size of the token bucket, defaults to 1
+		Burst int
+		// MaxInFlight caps the concurrent requests, zero means unlimited
+		MaxInFlight int
+	}
+
+	// limiter type enforces the RateLimit of one endpoint family, it is shared by every goroutine using the client
+	limiter struct {
+		mu     sync.Mutex
+		rate   float64
+		burst  float64
+		tokens float64
+		last   time.Time
+		// blockedUntil holds back every request after Klarna answered with 429
+		blockedUntil time.Time
+		slots        chan struct{}
+	}
+)
+
+// newLimiter function creates the limiter of the given limit, the zero RateLimit creates a limiter that only
+// applies the backoff after 429 responses
+func newLimiter(l RateLimit) *limiter {
+	burst := float64(l.Burst)
+	if 1 > burst {
+		burst = 1
+	}
+	lim := &limiter{
+		rate:   l.RequestsPerSecond,
+		burst:  burst,
+		tokens: burst,
+	}
+	if 0 < l.MaxInFlight {
+		lim.slots = make(chan struct{}, l.MaxInFlight)
+	}
+
+	return lim
+}
+
+// acquire method waits until the request may be sent, the returned function must be called once it is done
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if nil != l.slots {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if nil != l.slots {
+			<-l.slots
+		}
+	}
+
+	for {
+		wait := l.reserve(time.Now())
+		if 0 >= wait {
+			return release, nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			release()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve method takes a token when one is available, otherwise it returns how long to wait before trying again
+func (l *limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Before(l.blockedUntil) {
+		return l.blockedUntil.Sub(now)
+	}
+	if 0 >= l.rate {
+		return 0
+	}
+
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	if 1 <= l.tokens {
+		l.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// throttle method holds back every request of the family for the given duration
+func (l *limiter) throttle(d time.Duration) {
+	if 0 >= d {
+		d = defaultThrottleBackoff
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.blockedUntil) {
+		l.blockedUntil = until
+	}
+}
+
+// newLimiters function creates a limiter for every endpoint family
+func newLimiters(limits map[EndpointFamily]RateLimit) map[EndpointFamily]*limiter {
+	limiters := make(map[EndpointFamily]*limiter)
+	for _, family := range []EndpointFamily{CheckoutFamily, PaymentsFamily, OrderManagementFamily, OtherFamily} {
+		limiters[family] = newLimiter(limits[family])
+	}
+
+	return limiters
+}
@@ -0,0 +1,108 @@
+package go_klarna
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiter_TokenBucket(t *testing.T) {
+	assertions := assert.New(t)
+	lim := newLimiter(RateLimit{RequestsPerSecond: 10, Burst: 2})
+	now := time.Now()
+
+	assertions.Equal(time.Duration(0), lim.reserve(now))
+	assertions.Equal(time.Duration(0), lim.reserve(now))
+	assertions.Equal(100*time.Millisecond, lim.reserve(now))
+	assertions.Equal(time.Duration(0), lim.reserve(now.Add(100*time.Millisecond)))
+}
+
+func TestLimiter_Throttle(t *testing.T) {
+	assertions := assert.New(t)
+	lim := newLimiter(RateLimit{})
+
+	lim.throttle(50 * time.Millisecond)
+	start := time.Now()
+	release, err := lim.acquire(context.Background())
+	assertions.Nil(err)
+	release()
+	assertions.True(time.Since(start) >= 40*time.Millisecond)
+
+	lim.throttle(time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = lim.acquire(ctx)
+	assertions.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func TestClient_MaxInFlight(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	var current, peak int32
+	testingMux.HandleFunc("/ordermanagement/v1/orders/", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	})
+
+	uri, _ := url.Parse(testingServer.URL)
+	c := NewClient(Config{
+		BaseURL: uri,
+		RateLimits: map[EndpointFamily]RateLimit{
+			OrderManagementFamily: {MaxInFlight: 2},
+		},
+	})
+	srv := NewOrderManagement(c)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := srv.GetOrder("abc")
+			assertions.Nil(err)
+		}()
+	}
+	wg.Wait()
+
+	assertions.Equal(int32(2), atomic.LoadInt32(&peak))
+}
+
+func TestClient_TooManyRequestsThrottlesFamily(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc/captures", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	c := testingClient()
+	err := NewOrderManagement(c).CreateCapture("abc", &CreateCapture{})
+	assertions.ErrorIs(err, ErrRateLimited)
+	assertions.NotErrorIs(err, ServiceUnavailable)
+
+	blocked := c.limiters[OrderManagementFamily].reserve(time.Now())
+	assertions.True(blocked > 25*time.Second, "family is blocked for %s", blocked)
+	assertions.Equal(time.Duration(0), c.limiters[CheckoutFamily].reserve(time.Now()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = NewOrderManagement(c).GetOrderContext(ctx, "abc")
+	assertions.ErrorIs(err, context.DeadlineExceeded)
+}
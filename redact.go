@@ -0,0 +1,67 @@
+package go_klarna
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveFields lists the JSON fields holding personal data of the customer, their values never leave the client
+// through logs or debug output. They cover Address, CheckoutCustomer, CustomerInfo and OrderManagementCustomer
+var sensitiveFields = map[string]bool{
+	"given_name":                     true,
+	"family_name":                    true,
+	"email":                          true,
+	"phone":                          true,
+	"street_address":                 true,
+	"street_address2":                true,
+	"date_of_birth":                  true,
+	"last_four_ssn":                  true,
+	"national_identification_number": true,
+}
+
+// redactJSON function returns a copy of the JSON document with the values of the sensitive fields masked, content
+// which is not JSON is replaced by a short description as it can not be redacted reliably
+func redactJSON(data []byte) string {
+	if 0 == len(bytes.TrimSpace(data)) {
+		return ""
+	}
+
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); nil != err {
+		return fmt.Sprintf("[%d bytes of non JSON content]", len(data))
+	}
+
+	redacted, err := json.Marshal(redactValue(doc))
+	if nil != err {
+		return fmt.Sprintf("[%d bytes of content]", len(data))
+	}
+
+	return string(redacted)
+}
+
+// redactValue function walks a decoded JSON value masking the sensitive fields in place
+func redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, field := range value {
+			if sensitiveFields[k] {
+				if "" != field && nil != field {
+					value[k] = redacted
+				}
+				continue
+			}
+			value[k] = redactValue(field)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactValue(item)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,181 @@
+package go_klarna
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxDrainSize caps the amount of unread body bytes discarded before closing a response, larger bodies are simply
+// closed and their connection is not reused
+const maxDrainSize = 64 << 10
+
+type (
+	// request type describes a single call of one of the services against the Klarna API
+	request struct {
+		// op is the qualified operation name, e.g. "OrderManagement.CreateCapture"
+		op     string
+		method string
+		// route is the templated path, its placeholders are replaced by params in order
+		route  string
+		params []string
+		body   interface{}
+		// idempotent makes sure the request carries a Klarna-Idempotency-Key header
+		idempotent bool
+	}
+
+	// result type exposes the status and headers of a completed call, the body is already drained and closed
+	result struct {
+		StatusCode int
+		Header     http.Header
+	}
+)
+
+// path method expands the route of the request with its params
+func (r request) path() (string, error) {
+	var b strings.Builder
+	params := r.params
+	route := r.route
+	for {
+		start := strings.IndexByte(route, '{')
+		if 0 > start {
+			b.WriteString(route)
+			break
+		}
+		end := strings.IndexByte(route[start:], '}')
+		if 0 > end {
+			return "", fmt.Errorf("malformed route %q", r.route)
+		}
+		if 0 == len(params) {
+			return "", fmt.Errorf("missing %s for %s", route[start:start+end+1], r.op)
+		}
+		if "" == params[0] {
+			return "", fmt.Errorf("empty %s for %s", route[start:start+end+1], r.op)
+		}
+
+		b.WriteString(route[:start])
+		b.WriteString(url.PathEscape(params[0]))
+		params = params[1:]
+		route = route[start+end+1:]
+	}
+
+	return b.String(), nil
+}
+
+// param method returns the value the given placeholder of the route is expanded with
+func (r request) param(placeholder string) string {
+	route := r.route
+	for i := 0; i < len(r.params); i++ {
+		start := strings.IndexByte(route, '{')
+		if 0 > start {
+			break
+		}
+		end := strings.IndexByte(route[start:], '}')
+		if 0 > end {
+			break
+		}
+		if placeholder == route[start:start+end+1] {
+			return r.params[i]
+		}
+		route = route[start+end+1:]
+	}
+
+	return ""
+}
+
+// send function executes the request and discards the response body
+func send(ctx context.Context, c Client, r request) (*result, error) {
+	return execute[struct{}](ctx, c, r, nil)
+}
+
+// fetch function executes the request and decodes the response body into a new T
+func fetch[T any](ctx context.Context, c Client, r request) (*T, *result, error) {
+	v := new(T)
+	res, err := execute(ctx, c, r, v)
+	if nil != err {
+		return nil, res, err
+	}
+
+	return v, res, nil
+}
+
+// execute function executes the request through the given client, decodes the response body into the given value
+// unless it is nil, and always drains and closes the body
+func execute[T any](ctx context.Context, c Client, r request, into *T) (*result, error) {
+	path, err := r.path()
+	if nil != err {
+		return nil, err
+	}
+
+	ctx = withOperation(ctx, Operation{
+		Name:    r.op,
+		Route:   r.route,
+		OrderID: r.param("{order_id}"),
+	})
+	if r.idempotent {
+		ctx = ensureIdempotencyKey(ctx, r.method, path)
+	}
+
+	res, err := do(ctx, c, r, path)
+	if nil != err {
+		return nil, err
+	}
+	defer closeBody(res.Body)
+
+	meta := &result{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+	}
+	if nil == into || http.StatusNoContent == res.StatusCode {
+		return meta, nil
+	}
+
+	err = json.NewDecoder(res.Body).Decode(into)
+	if errors.Is(err, io.EOF) {
+		// an empty body is a valid answer, e.g. for a 201 Created
+		err = nil
+	}
+
+	return meta, err
+}
+
+// do function executes the request on the expanded path through the context aware methods of the client, or
+// through its plain methods when it does not implement ContextClient
+func do(ctx context.Context, c Client, r request, path string) (*http.Response, error) {
+	cc, ok := c.(ContextClient)
+	switch r.method {
+	case http.MethodGet:
+		if ok {
+			return cc.GetContext(ctx, path)
+		}
+		return c.Get(path)
+	case http.MethodPost:
+		if ok {
+			return cc.PostContext(ctx, path, r.body)
+		}
+		return c.Post(path, r.body)
+	case http.MethodPatch:
+		if ok {
+			return cc.PatchContext(ctx, path, r.body)
+		}
+		return c.Patch(path, r.body)
+	case http.MethodDelete:
+		if ok {
+			return cc.DeleteContext(ctx, path)
+		}
+		return c.Delete(path)
+	}
+
+	return nil, fmt.Errorf("unsupported method %s for %s", r.method, r.op)
+}
+
+// closeBody function drains what is left of a response body so the connection can be reused, then closes it
+func closeBody(body io.ReadCloser) {
+	_, _ = io.CopyN(io.Discard, body, maxDrainSize)
+	_ = body.Close()
+}
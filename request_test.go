@@ -0,0 +1,143 @@
+package go_klarna
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type trackedBody struct {
+	io.ReadCloser
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return b.ReadCloser.Close()
+}
+
+func TestRequest_Path(t *testing.T) {
+	assertions := assert.New(t)
+
+	path, err := request{
+		op:     "OrderManagement.GetCapture",
+		route:  OrderManagementEndpoint + "/{order_id}/captures/{capture_id}",
+		params: []string{"abc", "c/1"},
+	}.path()
+	assertions.Nil(err)
+	assertions.Equal("/ordermanagement/v1/orders/abc/captures/c%2F1", path)
+
+	_, err = request{op: "OrderManagement.GetOrder", route: OrderManagementEndpoint + "/{order_id}"}.path()
+	assertions.EqualError(err, "missing {order_id} for OrderManagement.GetOrder")
+
+	_, err = request{
+		op:     "OrderManagement.GetOrder",
+		route:  OrderManagementEndpoint + "/{order_id}",
+		params: []string{""},
+	}.path()
+	assertions.EqualError(err, "empty {order_id} for OrderManagement.GetOrder")
+}
+
+func TestExecute_ClosesBody(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	setupMux(assertions, "/ordermanagement/v1/orders/abc/acknowledge", nil, http.MethodPost, mockedResponse)
+
+	var body *trackedBody
+	uri, _ := url.Parse(testingServer.URL)
+	c := NewClient(Config{
+		BaseURL: uri,
+		Middleware: []Middleware{func(next Handler) Handler {
+			return func(op Operation, req *http.Request) (*http.Response, error) {
+				res, err := next(op, req)
+				if nil == err {
+					body = &trackedBody{ReadCloser: res.Body}
+					res.Body = body
+				}
+				return res, err
+			}
+		}},
+	})
+	err := NewOrderManagement(c).AcknowledgeOrder("abc")
+
+	assertions.Nil(err)
+	assertions.True(body.closed)
+}
+
+func TestExecute_ExposesResult(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc/captures", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Capture-Id", "cid-1")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	c := testingClient()
+	res, err := send(context.Background(), c, request{
+		op:     "OrderManagement.CreateCapture",
+		method: http.MethodPost,
+		route:  OrderManagementEndpoint + "/{order_id}/captures",
+		params: []string{"abc"},
+		body:   &CreateCapture{},
+	})
+
+	assertions.Nil(err)
+	assertions.Equal(http.StatusCreated, res.StatusCode)
+	assertions.Equal("cid-1", res.Header.Get("Capture-Id"))
+}
+
+// plainClient implements the Client without its context aware variants, like the implementations written against
+// the interface before ContextClient existed
+type plainClient struct {
+	calls []string
+}
+
+func (c *plainClient) Post(path string, _ interface{}) (*http.Response, error) {
+	return c.respond(http.MethodPost, path)
+}
+
+func (c *plainClient) Patch(path string, _ interface{}) (*http.Response, error) {
+	return c.respond(http.MethodPatch, path)
+}
+
+func (c *plainClient) Get(path string) (*http.Response, error) {
+	return c.respond(http.MethodGet, path)
+}
+
+func (c *plainClient) Delete(path string) (*http.Response, error) {
+	return c.respond(http.MethodDelete, path)
+}
+
+func (c *plainClient) respond(method, path string) (*http.Response, error) {
+	c.calls = append(c.calls, method+" "+path)
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"order_id":"abc"}`)),
+	}, nil
+}
+
+func TestExecute_PlainClient(t *testing.T) {
+	assertions := assert.New(t)
+
+	c := &plainClient{}
+	order, err := NewCheckoutSrv(c).RetrieveOrderContext(context.Background(), "abc")
+	assertions.Nil(err)
+	assertions.Equal("abc", order.ID)
+
+	err = NewOrderManagement(c).CancelOrder("abc")
+	assertions.Nil(err)
+	assertions.Equal([]string{
+		"GET /checkout/v3/orders/abc",
+		"POST /ordermanagement/v1/orders/abc/cancel",
+	}, c.calls)
+}
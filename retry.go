@@ -0,0 +1,112 @@
+package go_klarna
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMaxBackoff     = 5 * time.Second
+)
+
+// RetryPolicy type configures how failed requests are retried. Transport errors, 429 and 5xx responses are retried
+// using exponential backoff with jitter, a Retry-After header sent by Klarna takes precedence over the backoff.
+// POST and PATCH requests are only retried when they carry an idempotency key, see WithIdempotencyKey
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int
+	// InitialBackoff is the base delay before the first retry, defaults to 100ms
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts, defaults to 5s
+	MaxBackoff time.Duration
+}
+
+// shouldRetry method decides whether the failed attempt number should be retried
+func (p *RetryPolicy) shouldRetry(ctx context.Context, attempt int, method, idempotencyKey string, err error) bool {
+	if nil == p || attempt >= p.MaxAttempts || nil != ctx.Err() {
+		return false
+	}
+	if !isIdempotent(method) && "" == idempotencyKey {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return isRetryableStatus(apiErr.StatusCode)
+	}
+
+	return true
+}
+
+// backoff method computes the delay before the next attempt
+func (p *RetryPolicy) backoff(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && 0 < apiErr.RetryAfter {
+		return apiErr.RetryAfter
+	}
+
+	initial, max := p.InitialBackoff, p.MaxBackoff
+	if 0 >= initial {
+		initial = defaultInitialBackoff
+	}
+	if 0 >= max {
+		max = defaultMaxBackoff
+	}
+
+	d := initial << uint(attempt-1)
+	if d > max || 0 >= d {
+		d = max
+	}
+
+	// equal jitter, keeps at least half of the delay while spreading concurrent retries
+	half := d / 2
+
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// isIdempotent function tells whether the HTTP method can be safely repeated
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+
+	return false
+}
+
+// isRetryableStatus function tells whether a response status is worth another attempt
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// parseRetryAfter function parses the value of a Retry-After header, either delay seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if "" == value {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); nil == err {
+		if 0 > seconds {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); nil == err && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
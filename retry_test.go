@@ -0,0 +1,177 @@
+package go_klarna
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_RetryIdempotentRequest(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	attempts := 0
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"order_id":"abc"}`))
+	})
+
+	c := testingClient()
+	c.config.Retry = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	o, err := NewOrderManagement(c).GetOrder("abc")
+
+	assertions.Nil(err)
+	assertions.Equal("abc", o.ID)
+	assertions.Equal(3, attempts)
+}
+
+func TestClient_RetryKeepsIdempotencyKey(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	var keys []string
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc/captures", func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Klarna-Idempotency-Key"))
+		if len(keys) < 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	c := testingClient()
+	c.config.Retry = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	err := NewOrderManagement(c).CreateCapture("abc", &CreateCapture{})
+
+	assertions.Nil(err)
+	assertions.Len(keys, 2)
+	assertions.NotEmpty(keys[0])
+	assertions.Equal(keys[0], keys[1])
+}
+
+func TestClient_RetryCallerIdempotencyKey(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	var key string
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc/refunds", func(w http.ResponseWriter, r *http.Request) {
+		key = r.Header.Get("Klarna-Idempotency-Key")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	c := testingClient()
+	ctx := WithIdempotencyKey(context.Background(), "refund-shipment-42")
+	err := NewOrderManagement(c).CreateRefundContext(ctx, "abc", &OrderManagementRefund{})
+
+	assertions.Nil(err)
+	assertions.Equal("refund-shipment-42", key)
+}
+
+func TestClient_IdempotencyKeyBoundToOneRequest(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	keys := map[string][]string{}
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc/", func(w http.ResponseWriter, r *http.Request) {
+		keys[r.Method+" "+r.URL.Path] = append(keys[r.Method+" "+r.URL.Path], r.Header.Get(idempotencyKeyHeader))
+		if strings.HasSuffix(r.URL.Path, "/captures") {
+			w.Header().Set("Capture-Id", "cap-1")
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		if http.MethodGet == r.Method {
+			w.Write([]byte(`{"capture_id":"cap-1"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	srv := NewOrderManagement(testingClient())
+	ctx := WithIdempotencyKey(context.Background(), "capture-shipment-42")
+	_, err := srv.CaptureOrderAndGetContext(ctx, "abc", &CreateCapture{CapturedAmount: 100})
+	assertions.Nil(err)
+	_, err = srv.CaptureOrderContext(ctx, "abc", &CreateCapture{CapturedAmount: 100})
+	assertions.Nil(err)
+	assertions.Nil(srv.AddCaptureShippingInfoContext(ctx, "abc", "cap-1", nil))
+
+	captures := keys["POST /ordermanagement/v1/orders/abc/captures"]
+	assertions.Equal([]string{"capture-shipment-42", "capture-shipment-42"}, captures)
+	assertions.Equal([]string{""}, keys["GET /ordermanagement/v1/orders/abc/captures/cap-1"])
+	shippingInfo := keys["POST /ordermanagement/v1/orders/abc/captures/cap-1/shipping-info"]
+	assertions.Len(shippingInfo, 1)
+	assertions.NotEmpty(shippingInfo[0])
+	assertions.NotEqual("capture-shipment-42", shippingInfo[0])
+}
+
+func TestClient_NoRetryWithoutIdempotencyKey(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	attempts := 0
+	testingMux.HandleFunc("/checkout/v3/orders", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	c := testingClient()
+	c.config.Retry = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	err := NewCheckoutSrv(c).CreateNewOrder(&CheckoutOrder{})
+
+	assertions.ErrorIs(err, ServiceUnavailable)
+	assertions.Equal(1, attempts)
+}
+
+func TestClient_NoRetryOnClientError(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	attempts := 0
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	c := testingClient()
+	c.config.Retry = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	_, err := NewOrderManagement(c).GetOrder("abc")
+
+	assertions.ErrorIs(err, ErrOrderNotFound)
+	assertions.Equal(1, attempts)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	assertions := assert.New(t)
+	p := &RetryPolicy{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for attempt := 1; attempt <= 5; attempt++ {
+		d := p.backoff(attempt, ServiceUnavailable)
+		assertions.True(d >= 50*time.Millisecond, "backoff %s is too short", d)
+		assertions.True(d <= time.Second, "backoff %s exceeds the maximum", d)
+	}
+
+	throttled := &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 3 * time.Second}
+	assertions.Equal(3*time.Second, p.backoff(1, throttled))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assertions := assert.New(t)
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	assertions.Equal(2*time.Second, parseRetryAfter("2", now))
+	assertions.Equal(30*time.Second, parseRetryAfter("Wed, 01 Jan 2020 12:00:30 GMT", now))
+	assertions.Equal(time.Duration(0), parseRetryAfter("", now))
+	assertions.Equal(time.Duration(0), parseRetryAfter("soon", now))
+}
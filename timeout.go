@@ -0,0 +1,51 @@
+package go_klarna
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// defaultTimeout is the deadline budget of an operation when Config.Timeout is not set
+const defaultTimeout = time.Second * 5
+
+// cancelOnClose type releases the deadline budget of an operation once its response body is closed, the body may
+// still be read after the client returned the response
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+// Close method closes the body and then releases the deadline budget
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.cancel)
+
+	return err
+}
+
+// timeoutFor method returns the deadline budget of an operation, in order of precedence: the timeout of the
+// operation, the one of its endpoint family, Config.Timeout. Zero or less means no budget
+func (c *client) timeoutFor(op Operation) time.Duration {
+	if d, ok := c.config.OperationTimeouts[op.Name]; ok {
+		return d
+	}
+	if d, ok := c.config.FamilyTimeouts[op.Family()]; ok {
+		return d
+	}
+
+	return c.config.Timeout
+}
+
+// withBudget method returns a copy of the context expiring once the deadline budget of the operation is spent, a
+// shorter deadline of the given context is kept
+func (c *client) withBudget(ctx context.Context, op Operation) (context.Context, context.CancelFunc) {
+	d := c.timeoutFor(op)
+	if 0 >= d {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,91 @@
+package go_klarna
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_TimeoutFor(t *testing.T) {
+	assertions := assert.New(t)
+
+	c := NewClient(Config{
+		OperationTimeouts: map[string]time.Duration{"Checkout.RetrieveOrder": time.Second},
+		FamilyTimeouts:    map[EndpointFamily]time.Duration{CheckoutFamily: 2 * time.Second, OtherFamily: -1},
+	}).(*client)
+
+	assertions.Equal(time.Second, c.timeoutFor(Operation{Name: "Checkout.RetrieveOrder", Route: checkoutEndPoint}))
+	assertions.Equal(2*time.Second, c.timeoutFor(Operation{Name: "Checkout.UpdateOrder", Route: checkoutEndPoint}))
+	assertions.Equal(defaultTimeout, c.timeoutFor(Operation{Route: OrderManagementEndpoint}))
+	assertions.Equal(time.Duration(-1), c.timeoutFor(Operation{Route: "/settlements/v1/reports"}))
+}
+
+func TestClient_DeadlineBudgetAcrossRetries(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	var attempts int32
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(30 * time.Millisecond):
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	uri, _ := url.Parse(testingServer.URL)
+	c := NewClient(Config{
+		BaseURL:     uri,
+		APIUsername: "someUser",
+		APIPassword: "somePass",
+		Timeout:     80 * time.Millisecond,
+		Retry:       &RetryPolicy{MaxAttempts: 10, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	})
+
+	start := time.Now()
+	_, err := NewOrderManagement(c).GetOrder("abc")
+	assertions.NotNil(err)
+	assertions.Less(time.Since(start), 300*time.Millisecond)
+	assertions.LessOrEqual(atomic.LoadInt32(&attempts), int32(4))
+}
+
+func TestClient_BudgetLastsUntilBodyClosed(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	testingMux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong"))
+	})
+
+	var reqCtx context.Context
+	uri, _ := url.Parse(testingServer.URL)
+	c := NewClient(Config{
+		BaseURL: uri,
+		Middleware: []Middleware{func(next Handler) Handler {
+			return func(op Operation, req *http.Request) (*http.Response, error) {
+				reqCtx = req.Context()
+				return next(op, req)
+			}
+		}},
+	})
+
+	res, err := c.GetContext(context.Background(), "/ping")
+	assertions.Nil(err)
+	assertions.Nil(reqCtx.Err())
+
+	body, err := io.ReadAll(res.Body)
+	assertions.Nil(err)
+	assertions.Equal("pong", string(body))
+
+	assertions.Nil(res.Body.Close())
+	assertions.ErrorIs(reqCtx.Err(), context.Canceled)
+	assertions.Nil(res.Body.Close())
+}
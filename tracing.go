@@ -0,0 +1,59 @@
+package go_klarna
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+const (
+	tracerName = "github.com/Flaconi/go-klarna"
+
+	// span attributes, the HTTP ones follow the OpenTelemetry semantic conventions
+	attrHTTPMethod    = attribute.Key("http.request.method")
+	attrHTTPRoute     = attribute.Key("http.route")
+	attrHTTPStatus    = attribute.Key("http.response.status_code")
+	attrCorrelationID = attribute.Key("klarna.correlation_id")
+	attrOrderID       = attribute.Key("klarna.order_id")
+	attrRetryCount    = attribute.Key("klarna.retry_count")
+	attrErrorCode     = attribute.Key("klarna.error_code")
+)
+
+// startSpan method starts the client span of an operation as a child of the span carried by the context
+func (c *client) startSpan(ctx context.Context, op Operation, method string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attrHTTPMethod.String(method),
+		attrHTTPRoute.String(op.Route),
+	}
+	if "" != op.OrderID {
+		attrs = append(attrs, attrOrderID.String(op.OrderID))
+	}
+
+	return c.tracer.Start(ctx, op.Name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan function records the outcome of an operation on its span and ends it
+func endSpan(span trace.Span, res *http.Response, attempts int, err error) {
+	defer span.End()
+
+	if 1 < attempts {
+		span.SetAttributes(attrRetryCount.Int(attempts - 1))
+	}
+
+	o := outcomeOf(res, err)
+	if 0 != o.status {
+		span.SetAttributes(attrHTTPStatus.Int(o.status))
+	}
+	if "" != o.correlationID {
+		span.SetAttributes(attrCorrelationID.String(o.correlationID))
+	}
+	if "" != o.errorCode {
+		span.SetAttributes(attrErrorCode.String(o.errorCode))
+	}
+	if nil != err {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
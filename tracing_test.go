@@ -0,0 +1,106 @@
+package go_klarna
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func tracingClient(exporter *tracetest.InMemoryExporter) (*client, *sdktrace.TracerProvider) {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	uri, _ := url.Parse(testingServer.URL)
+
+	return NewClient(Config{
+		BaseURL:        uri,
+		APIUsername:    "someUser",
+		APIPassword:    "somePass",
+		TracerProvider: tp,
+		Retry:          &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+	}).(*client), tp
+}
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+
+	return attrs
+}
+
+func TestClient_TracingSpan(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	attempts := 0
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc/captures", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Klarna-Correlation-Id", "corr-1")
+		if 1 == attempts {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	exporter := tracetest.NewInMemoryExporter()
+	c, tp := tracingClient(exporter)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "ship-order")
+	err := NewOrderManagement(c).CreateCaptureContext(ctx, "abc", &CreateCapture{})
+	parent.End()
+	assertions.Nil(err)
+
+	spans := exporter.GetSpans()
+	assertions.Len(spans, 2)
+	span := spans[0]
+	assertions.Equal("OrderManagement.CreateCapture", span.Name)
+	assertions.Equal(parent.SpanContext().SpanID(), span.Parent.SpanID())
+
+	attrs := spanAttributes(span)
+	assertions.Equal("POST", attrs[attrHTTPMethod].AsString())
+	assertions.Equal("/ordermanagement/v1/orders/{order_id}/captures", attrs[attrHTTPRoute].AsString())
+	assertions.Equal(int64(http.StatusCreated), attrs[attrHTTPStatus].AsInt64())
+	assertions.Equal("corr-1", attrs[attrCorrelationID].AsString())
+	assertions.Equal("abc", attrs[attrOrderID].AsString())
+	assertions.Equal(int64(1), attrs[attrRetryCount].AsInt64())
+}
+
+func TestClient_TracingErrorSpan(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	setupErrorMux(
+		"/checkout/v3/orders/abc",
+		http.StatusNotFound,
+		`{"error_code":"NO_SUCH_ORDER","correlation_id":"corr-2"}`,
+	)
+
+	exporter := tracetest.NewInMemoryExporter()
+	c, _ := tracingClient(exporter)
+	_, err := NewCheckoutSrv(c).RetrieveOrder("abc")
+	assertions.ErrorIs(err, ErrOrderNotFound)
+
+	spans := exporter.GetSpans()
+	assertions.Len(spans, 1)
+	span := spans[0]
+	assertions.Equal("Checkout.RetrieveOrder", span.Name)
+	assertions.Equal(codes.Error, span.Status.Code)
+
+	attrs := spanAttributes(span)
+	assertions.Equal(int64(http.StatusNotFound), attrs[attrHTTPStatus].AsInt64())
+	assertions.Equal("corr-2", attrs[attrCorrelationID].AsString())
+	assertions.Equal("NO_SUCH_ORDER", attrs[attrErrorCode].AsString())
+	assertions.Equal("abc", attrs[attrOrderID].AsString())
+	_, retried := attrs[attrRetryCount]
+	assertions.False(retried)
+}
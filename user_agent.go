@@ -0,0 +1,54 @@
+package go_klarna
+
+import (
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// Version of the library, it is reported to Klarna in the User-Agent header of every request
+const Version = "1.0.0"
+
+// userAgent function builds the User-Agent header identifying the library, the Go runtime and the application and
+// platform of the configuration, e.g. "go-klarna/1.0.0 (go1.21.5; linux/amd64) shop/2.3.0 Shopware/6.5"
+func userAgent(c Config) string {
+	var b strings.Builder
+	b.WriteString(product("go-klarna", Version))
+	b.WriteString(" (" + runtime.Version() + "; " + runtime.GOOS + "/" + runtime.GOARCH + ")")
+	if "" != c.AppName {
+		b.WriteString(" " + product(c.AppName, c.AppVersion))
+	}
+	if "" != c.Platform {
+		b.WriteString(" " + product(c.Platform, c.PlatformVersion))
+	}
+
+	return b.String()
+}
+
+// product function renders a User-Agent product token, characters not allowed in a token are replaced
+func product(name, version string) string {
+	token := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r > ' ' && r < 0x7f && !strings.ContainsRune(`()<>@,;:\"/[]?={}`, r) {
+				return r
+			}
+			return '_'
+		}, s)
+	}
+	if "" == version {
+		return token(name)
+	}
+
+	return token(name) + "/" + token(version)
+}
+
+// setIdentification method adds the headers of the configuration and the User-Agent to the request, the headers of
+// the library itself always take precedence
+func (c *client) setIdentification(req *http.Request) {
+	for name, values := range c.config.Headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+}
@@ -0,0 +1,53 @@
+package go_klarna
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"runtime"
+	"testing"
+)
+
+func TestUserAgent(t *testing.T) {
+	assertions := assert.New(t)
+
+	base := "go-klarna/" + Version + " (" + runtime.Version() + "; " + runtime.GOOS + "/" + runtime.GOARCH + ")"
+	assertions.Equal(base, userAgent(Config{}))
+	assertions.Equal(
+		base+" shop/2.3.0 Shopware/6.5",
+		userAgent(Config{AppName: "shop", AppVersion: "2.3.0", Platform: "Shopware", PlatformVersion: "6.5"}),
+	)
+	assertions.Equal(base+" my_shop", userAgent(Config{AppName: "my shop"}))
+}
+
+func TestClient_IdentificationHeaders(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	var received http.Header
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc", func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+		w.Write([]byte(`{"order_id":"abc"}`))
+	})
+
+	uri, _ := url.Parse(testingServer.URL)
+	c := NewClient(Config{
+		BaseURL:     uri,
+		APIUsername: "someUser",
+		APIPassword: "somePass",
+		AppName:     "shop",
+		AppVersion:  "2.3.0",
+		Headers: http.Header{
+			"Klarna-Partner": []string{"partner-1"},
+			"Content-Type":   []string{"text/plain"},
+		},
+	})
+	_, err := NewOrderManagement(c).GetOrder("abc")
+	assertions.Nil(err)
+
+	assertions.Contains(received.Get("User-Agent"), "go-klarna/"+Version)
+	assertions.Contains(received.Get("User-Agent"), "shop/2.3.0")
+	assertions.Equal("partner-1", received.Get("Klarna-Partner"))
+	assertions.Equal([]string{"application/json"}, received.Values("Content-Type"))
+}
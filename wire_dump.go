@@ -0,0 +1,244 @@
+package go_klarna
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maskedHeaders lists the headers whose values never show up in a wire dump
+var maskedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+type (
+	// wireDump type serializes the entries written to the dump writer, the writer does not need to be safe for
+	// concurrent use
+	wireDump struct {
+		mu sync.Mutex
+		w  io.Writer
+	}
+
+	// RotatingFile type is an io.WriteCloser appending to a file which is rotated once it exceeds its maximum size,
+	// the rotated files are kept as path.1, path.2 and so on, path.1 being the most recent one
+	RotatingFile struct {
+		mu         sync.Mutex
+		path       string
+		maxSize    int64
+		maxBackups int
+		file       *os.File
+		size       int64
+	}
+)
+
+// dumpWire method is the internal middleware writing every exchange with Klarna, headers, bodies and timing, to
+// Config.WireDump. Credentials are masked and the personal data of the customer is redacted
+func (c *client) dumpWire(next Handler) Handler {
+	return func(op Operation, req *http.Request) (*http.Response, error) {
+		reqBody, err := peekRequestBody(req)
+		if nil != err {
+			return nil, err
+		}
+
+		start := time.Now()
+		res, err := next(op, req)
+		duration := time.Since(start)
+
+		var b bytes.Buffer
+		if nil != err {
+			writeEntryHeader(&b, "", op, start, duration)
+			writeRequest(&b, req, reqBody)
+			fmt.Fprintf(&b, "<-- failed: %s\n\n", err)
+			c.wire.write(b.Bytes())
+			return nil, err
+		}
+
+		resBody, err := peekResponseBody(res)
+		if nil != err {
+			return nil, err
+		}
+
+		writeEntryHeader(&b, correlationIDOf(res, resBody), op, start, duration)
+		writeRequest(&b, req, reqBody)
+		fmt.Fprintf(&b, "<-- %s %s\n", res.Proto, res.Status)
+		writeHeaders(&b, res.Header)
+		writeBody(&b, resBody)
+		c.wire.write(b.Bytes())
+
+		return res, nil
+	}
+}
+
+// write method writes a whole entry at once
+func (d *wireDump) write(entry []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, _ = d.w.Write(entry)
+}
+
+// correlationIDOf function returns the correlation id of a response, taken from its header or else from its body
+func correlationIDOf(res *http.Response, body []byte) string {
+	if id := res.Header.Get(correlationIDHeader); "" != id {
+		return id
+	}
+
+	var payload struct {
+		CorrelationID string `json:"correlation_id"`
+	}
+	_ = json.Unmarshal(body, &payload)
+
+	return payload.CorrelationID
+}
+
+// writeEntryHeader function writes the line introducing an exchange
+func writeEntryHeader(b *bytes.Buffer, correlationID string, op Operation, start time.Time, duration time.Duration) {
+	if "" == correlationID {
+		correlationID = "none"
+	}
+	fmt.Fprintf(
+		b,
+		"=== correlation_id=%s operation=%q started=%s duration=%s\n",
+		correlationID,
+		op.Name,
+		start.UTC().Format(time.RFC3339Nano),
+		duration,
+	)
+}
+
+// writeRequest function writes the request line, headers and body of a request
+func writeRequest(b *bytes.Buffer, req *http.Request, body []byte) {
+	fmt.Fprintf(b, "--> %s %s\n", req.Method, req.URL.String())
+	writeHeaders(b, req.Header)
+	writeBody(b, body)
+}
+
+// writeHeaders function writes the headers sorted by name, with the masked ones redacted
+func writeHeaders(b *bytes.Buffer, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, value := range header[name] {
+			if maskedHeaders[http.CanonicalHeaderKey(name)] {
+				value = redacted
+			}
+			fmt.Fprintf(b, "%s: %s\n", name, value)
+		}
+	}
+}
+
+// writeBody function writes a redacted body, indented when it is JSON
+func writeBody(b *bytes.Buffer, body []byte) {
+	b.WriteString("\n")
+	content := redactBody(body)
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, []byte(content), "", "  "); nil == err {
+		content = indented.String()
+	}
+	if "" != content {
+		b.WriteString(strings.TrimRight(content, "\n"))
+		b.WriteString("\n\n")
+	}
+}
+
+// NewRotatingFile factory method opens the file at the given path for appending, it is rotated before a write would
+// make it exceed maxSize bytes and at most maxBackups rotated files are kept
+func NewRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	f := &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	if err := f.open(); nil != err {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Write method appends to the file, rotating it first when needed
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if nil == f.file {
+		return 0, os.ErrClosed
+	}
+	if 0 < f.size && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); nil != err {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+
+	return n, err
+}
+
+// Close method closes the file
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if nil == f.file {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+
+	return err
+}
+
+// open method opens the file for appending
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if nil != err {
+		return err
+	}
+	info, err := file.Stat()
+	if nil != err {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+
+	return nil
+}
+
+// rotate method shifts the rotated files by one, dropping the oldest one, and starts a new file
+func (f *RotatingFile) rotate() error {
+	err := f.file.Close()
+	f.file = nil
+	if nil != err {
+		return err
+	}
+
+	_ = os.Remove(fmt.Sprintf("%s.%d", f.path, f.maxBackups))
+	for i := f.maxBackups - 1; 0 < i; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+	}
+	if 0 < f.maxBackups {
+		if err := os.Rename(f.path, f.path+".1"); nil != err {
+			return err
+		}
+	} else if err := os.Remove(f.path); nil != err {
+		return err
+	}
+
+	return f.open()
+}
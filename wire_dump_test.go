@@ -0,0 +1,77 @@
+package go_klarna
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClient_WireDump(t *testing.T) {
+	setupServer()
+	defer tearDown()
+
+	assertions := assert.New(t)
+	testingMux.HandleFunc("/ordermanagement/v1/orders/abc/customer-details", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Klarna-Correlation-Id", "corr-1")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	setupErrorMux("/ordermanagement/v1/orders/cba", http.StatusNotFound, `{"error_code":"NO_SUCH_ORDER"}`)
+
+	var dump bytes.Buffer
+	uri, _ := url.Parse(testingServer.URL)
+	srv := NewOrderManagement(NewClient(Config{
+		BaseURL:     uri,
+		APIUsername: "someUser",
+		APIPassword: "somePass",
+		WireDump:    &dump,
+	}))
+
+	err := srv.UpdateCustomerAddress("abc", &CustomerAddress{
+		ShippingAddress: &Address{GivenName: "Jane", City: "Berlin"},
+	})
+	assertions.Nil(err)
+	_, err = srv.GetOrder("cba")
+	assertions.ErrorIs(err, ErrOrderNotFound)
+
+	out := dump.String()
+	assertions.Contains(out, `=== correlation_id=corr-1 operation="OrderManagement.UpdateCustomerAddress"`)
+	assertions.Contains(out, "--> POST "+testingServer.URL+"/ordermanagement/v1/orders/abc/customer-details")
+	assertions.Contains(out, "Authorization: [REDACTED]")
+	assertions.Contains(out, `"given_name": "[REDACTED]"`)
+	assertions.Contains(out, `"city": "Berlin"`)
+	assertions.Contains(out, "<-- HTTP/1.1 204 No Content")
+	assertions.Contains(out, `=== correlation_id=header-correlation-id operation="OrderManagement.GetOrder"`)
+	assertions.Contains(out, `"error_code": "NO_SUCH_ORDER"`)
+	assertions.NotContains(out, "Jane")
+	assertions.NotContains(out, "c29tZVVzZXI6c29tZVBhc3M=")
+}
+
+func TestRotatingFile(t *testing.T) {
+	assertions := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "klarna.dump")
+	f, err := NewRotatingFile(path, 10, 2)
+	assertions.Nil(err)
+
+	for _, entry := range []string{"first-", "second-", "third-", "fourth-"} {
+		_, err = f.Write([]byte(entry))
+		assertions.Nil(err)
+	}
+	assertions.Nil(f.Close())
+
+	current, _ := os.ReadFile(path)
+	first, _ := os.ReadFile(path + ".1")
+	second, _ := os.ReadFile(path + ".2")
+	assertions.Equal("fourth-", string(current))
+	assertions.Equal("third-", string(first))
+	assertions.Equal("second-", string(second))
+	_, err = os.Stat(path + ".3")
+	assertions.ErrorIs(err, os.ErrNotExist)
+
+	_, err = f.Write([]byte("closed"))
+	assertions.ErrorIs(err, os.ErrClosed)
+}